@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"monitor/internal/api"
+	database "monitor/internal/db"
+	"monitor/internal/metrics"
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+)
+
+// adminController holds the commands sent through the admin API, and whether
+// syncing is paused. Commands are run by the sync loop itself, so that they
+// never run while a checkpoint is being processed.
+type adminController struct {
+	// commands receives the commands sent through the admin API
+	commands chan api.AdminCommand
+
+	// paused and the sync mode before pausing are only accessed by the sync
+	// loop
+	paused      bool
+	resumedMode string
+}
+
+// newAdminController returns an adminController with syncing not paused.
+func newAdminController() *adminController {
+	return &adminController{
+		commands: make(chan api.AdminCommand),
+	}
+}
+
+// interruptible returns a context derived from the passed one, which is
+// cancelled as soon as an admin command is received, so that long syncs stop
+// at the next checkpoint to run it. The returned function stops waiting for
+// commands, and returns the one received, if any.
+func (a *adminController) interruptible(ctx context.Context) (context.Context, func() *api.AdminCommand) {
+	syncCtx, cancel := context.WithCancel(ctx)
+	received := make(chan *api.AdminCommand, 1)
+
+	go func() {
+		select {
+		case command := <-a.commands:
+			cancel()
+			received <- &command
+		case <-syncCtx.Done():
+			received <- nil
+		}
+	}()
+
+	return syncCtx, func() *api.AdminCommand {
+		cancel()
+		return <-received
+	}
+}
+
+// run runs the passed command, and sends its result back to the admin API.
+// nextBlock is the next block the sync loop will process, so every block
+// before it has already been processed.
+func (a *adminController) run(ctx context.Context, command api.AdminCommand, nextBlock uint64) {
+	slog.Info("Running admin command", "action", command.Action)
+
+	var result api.AdminResult
+	switch command.Action {
+	case api.ADMIN_PAUSE:
+		if a.paused {
+			result.Message = "syncing is already paused"
+			break
+		}
+		a.paused = true
+		a.resumedMode = telemetry.GetSyncState().Mode
+		telemetry.SetSyncMode(telemetry.SYNC_MODE_PAUSED)
+		result.Message = "syncing paused"
+		slog.Warn("Syncing paused through the admin API", "block", nextBlock)
+	case api.ADMIN_RESUME:
+		if !a.paused {
+			result.Message = "syncing is not paused"
+			break
+		}
+		a.paused = false
+		telemetry.SetSyncMode(a.resumedMode)
+		result.Message = "syncing resumed"
+		slog.Info("Syncing resumed through the admin API", "block", nextBlock)
+	case api.ADMIN_RESCAN:
+		result.Message, result.Err = rescanBlocks(ctx, command.StartBlock, command.EndBlock, nextBlock)
+	case api.ADMIN_VALIDATORS:
+		result.Message, result.Err = refetchValidators(command.Block, nextBlock)
+	case api.ADMIN_PERFORMANCE_BENCHMARK:
+		result.Message, result.Err = recalculatePerformanceBenchmark()
+	case api.ADMIN_METRICS:
+		result.Err = refreshMetrics()
+		result.Message = "metrics refreshed"
+	}
+
+	if result.Err != nil {
+		slog.Error("Admin command failed", "action", command.Action, "error", result.Err)
+	}
+	command.Result <- result
+}
+
+// rescanBlocks processes the checkpoints submitted between the passed blocks
+// again, replacing whatever was stored for them. Only blocks before nextBlock
+// can be rescanned. Checkpoints too far back for their performance benchmark
+// to be recalculated keep the one stored, and the performance benchmark of the
+// last checkpoint is recalculated afterwards, as it depends on the signers of
+// the previous 700 checkpoints.
+func rescanBlocks(ctx context.Context, startBlock uint64, endBlock uint64, nextBlock uint64) (string, error) {
+	if endBlock >= nextBlock {
+		return "", &utils.AdminCommandError{GenericError: utils.GenericError{Message: fmt.Sprintf("only blocks before %d have been processed", nextBlock)}}
+	}
+
+	newHeaderBlockEvents, err := utils.DecodeEvents(startBlock, endBlock)
+	if err != nil {
+		switch err.(type) {
+		case *utils.NoLogsFoundError:
+			return "no checkpoints were found in block range", nil
+		default:
+			return "", err
+		}
+	}
+
+	slog.Info("Rescanning checkpoints", "start_block", startBlock, "end_block", endBlock, "checkpoints", len(newHeaderBlockEvents))
+	for _, newEvent := range newHeaderBlockEvents {
+		// only stop in between checkpoints
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		checkpointNumber := newEvent.HeaderBlockId.Uint64()
+		previousPB, pbErr := database.GetPBAtCheckpoint(int(checkpointNumber))
+
+		err = database.DeleteCheckpoint(checkpointNumber)
+		if err != nil {
+			return "", err
+		}
+
+		pb, err := processCheckpoint(newEvent)
+		if err != nil {
+			rollbackErr := database.DeleteCheckpoint(checkpointNumber)
+			if rollbackErr != nil {
+				slog.Error("Could not roll back checkpoint", "checkpoint", checkpointNumber, "error", rollbackErr)
+			}
+
+			// the checkpoints rescanned so far may have changed, so the
+			// running counters and totals no longer match them
+			_, verifyErr := metrics.VerifyCounters(true)
+			if verifyErr != nil {
+				slog.Error("Could not recount the running counters", "error", verifyErr)
+			}
+
+			// and the validators were updated as of the rescanned blocks
+			validatorsErr := updateValidatorsAt(nextBlock - 1)
+			if validatorsErr != nil {
+				slog.Error("Could not update the validators as of the last block processed", "error", validatorsErr)
+			}
+			return "", fmt.Errorf("checkpoint %d could not be processed, rescan it again: %w", checkpointNumber, err)
+		}
+
+		if pb == 0 && pbErr == nil {
+			err = database.InsertPerformanceBenchmark(previousPB, int(checkpointNumber))
+			if err != nil {
+				return "", err
+			}
+		}
+
+		slog.Info("Rescanned checkpoint", "checkpoint", checkpointNumber, "block", newEvent.BlockNumber)
+	}
+
+	// processing each checkpoint updated the validators as of its block, so
+	// bring them back to the last block processed
+	err = updateValidatorsAt(nextBlock - 1)
+	if err != nil {
+		return "", err
+	}
+
+	// verify the rescanned checkpoints again, as their verification was
+	// removed along with them
+	if utils.GetConfig().BorRpcUrl != "" && len(newHeaderBlockEvents) > 0 {
+		err = rewindVerification(int(newHeaderBlockEvents[0].HeaderBlockId.Uint64()))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// look for checkpoint submissions which reverted in the same range
+	if utils.GetConfig().ScanFailedSubmissions {
+		err = scanFailedSubmissions(startBlock, endBlock)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err = recalculatePerformanceBenchmark()
+	if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+		default:
+			return "", err
+		}
+	}
+
+	err = refreshMetrics()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("rescanned %d checkpoints", len(newHeaderBlockEvents)), nil
+}
+
+// refetchValidators fetches the validator set at the passed block, or at the
+// last block processed if 0, and updates the validators in the database with
+// it. Only blocks before nextBlock can be used.
+func refetchValidators(block uint64, nextBlock uint64) (string, error) {
+	if nextBlock == 0 {
+		return "", &utils.AdminCommandError{GenericError: utils.GenericError{Message: "syncing has not started yet"}}
+	}
+	if block == 0 {
+		block = nextBlock - 1
+	}
+	if block >= nextBlock {
+		return "", &utils.AdminCommandError{GenericError: utils.GenericError{Message: fmt.Sprintf("only blocks before %d have been processed", nextBlock)}}
+	}
+
+	err := updateValidatorsAt(block)
+	if err != nil {
+		return "", err
+	}
+
+	err = refreshMetrics()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("validators updated as of block %d", block), nil
+}
+
+// updateValidatorsAt updates the validators in the database with the validator
+// set at the passed block.
+func updateValidatorsAt(block uint64) error {
+	// the last checkpoint is used to find the validators which are deactivated
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		lastCheckpoint = 0
+	} else if err != nil {
+		return err
+	}
+
+	return database.UpdateValidatorsDB(block, uint64(lastCheckpoint))
+}
+
+// recalculatePerformanceBenchmark calculates the performance benchmark of the
+// last checkpoint again from the signers of the previous 700 checkpoints, and
+// replaces the stored one.
+func recalculatePerformanceBenchmark() (string, error) {
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		return "", &utils.AdminCommandError{GenericError: utils.GenericError{Message: "no checkpoints processed yet"}}
+	} else if err != nil {
+		return "", err
+	}
+
+	pb, err := calculateAndInsertPerformanceBenchmark700(uint64(lastCheckpoint))
+	if err != nil {
+		return "", err
+	}
+	metrics.CurrentPerformanceBenchmark.Set(pb)
+
+	err = metrics.UpdateCheckpointsSignedMetrics()
+	if err != nil {
+		return "", err
+	}
+
+	slog.Info("Recalculated performance benchmark", "checkpoint", lastCheckpoint, "pb", pb)
+	return fmt.Sprintf("performance benchmark of checkpoint %d is %v", lastCheckpoint, pb), nil
+}
+
+// refreshMetrics drops the series of all validators and sets every metric
+// again from the database, as of the last checkpoint processed. The running
+// counters are recounted wherever they do not match the database, and the
+// running totals kept in memory are counted again in full.
+func refreshMetrics() error {
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		return &utils.AdminCommandError{GenericError: utils.GenericError{Message: "no checkpoints processed yet"}}
+	} else if err != nil {
+		return err
+	}
+	metrics.CurrentCheckpoint.Set(float64(lastCheckpoint))
+
+	pb, err := database.GetPBAtCheckpoint(lastCheckpoint)
+	if err == nil {
+		metrics.CurrentPerformanceBenchmark.Set(pb)
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	mismatches, err := metrics.VerifyCounters(true)
+	if err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		slog.Info("Running counters did not match the database and were recounted", "mismatches", mismatches)
+	}
+
+	metrics.ResetValidatorMetrics()
+	err = metrics.UpdateCheckpointsSignedMetrics()
+	if err != nil {
+		return err
+	}
+
+	err = metrics.UpdateFailedSubmissionMetrics()
+	if err != nil {
+		return err
+	}
+
+	// balances do not affect the rest of the metrics, as when syncing
+	err = metrics.UpdateBalanceMetrics(true)
+	if err != nil {
+		slog.Warn("Could not update the ETH balance of the tracked signers", "error", err)
+	}
+
+	return metrics.UpdateStallMetrics()
+}
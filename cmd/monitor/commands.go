@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"monitor/internal/analysis"
+	database "monitor/internal/db"
+	"monitor/internal/metrics"
+	"monitor/internal/utils"
+	"os"
+	"time"
+)
+
+// configCommand runs the config subcommand with the passed arguments, and
+// returns the exit code. The only supported action is check, which validates a
+// config file without starting the monitor.
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "Usage: polygon_monitor config check [--config=/path/to/config]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.json", "Path to config file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	// allow passing the path without the flag
+	if flags.NArg() > 0 {
+		*configPath = flags.Arg(0)
+	}
+
+	_, err := utils.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config %s is not valid:\n", *configPath)
+
+		// list each problem on its own line
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			for _, fieldErr := range joined.Unwrap() {
+				fmt.Fprintf(os.Stderr, "  - %v\n", fieldErr)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "  - %v\n", err)
+		}
+		return 1
+	}
+
+	fmt.Printf("Config %s is valid.\n", *configPath)
+	return 0
+}
+
+// countersCommand runs the counters subcommand with the passed arguments, and
+// returns the exit code. The only supported action is verify, which compares
+// the running counters saved in the database with a full recount.
+func countersCommand(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "Usage: polygon_monitor counters verify [--config=/path/to/config]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("counters verify", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.json", "Path to config file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if code := openDatabaseForCommand(*configPath); code != 0 {
+		return code
+	}
+
+	mismatches, err := metrics.VerifyCounters(false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not verify counters: %v\n", err)
+		return 2
+	}
+
+	if mismatches > 0 {
+		fmt.Printf("Found %d mismatched counters. They are recounted the next time the monitor starts.\n", mismatches)
+		return 1
+	}
+
+	fmt.Println("All counters match a full recount.")
+	return 0
+}
+
+// openDatabaseForCommand loads the config in the passed path and makes sure
+// the database exists and is up to date, for subcommands which read from it.
+// It returns an exit code other than 0 on failure.
+func openDatabaseForCommand(configPath string) int {
+	config, err := utils.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config %s is not valid: %v\n", configPath, err)
+		return 2
+	}
+	utils.SetConfig(config)
+
+	if _, err := utils.CheckIfDBExists(); err != nil {
+		fmt.Fprintf(os.Stderr, "Database %s does not exist\n", config.DatabaseLocation)
+		return 2
+	}
+
+	err = database.MigrateDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not update database: %v\n", err)
+		return 2
+	}
+
+	return 0
+}
+
+// simulateCommand runs the simulate subcommand with the passed arguments, and
+// returns the exit code. It projects the performance benchmark and the
+// performance of a validator forward, under the chosen scenario.
+func simulateCommand(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.json", "Path to config file")
+	validatorId := flags.Int("validator", 0, "ID of the validator to simulate")
+	checkpoints := flags.Int("checkpoints", utils.PB_WINDOW, "Number of checkpoints to project forward")
+	scenario := flags.String("scenario", analysis.SCENARIO_MISS, "Scenario to simulate: miss (the validator misses every checkpoint), current (the set keeps its current signing rate) or sign (the validator signs every checkpoint)")
+	outputJSON := flags.Bool("json", false, "Output the full result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *validatorId <= 0 {
+		fmt.Fprintln(os.Stderr, "Usage: polygon_monitor simulate --validator=ID [--checkpoints=700] [--scenario=miss|current|sign] [--json] [--config=/path/to/config]")
+		return 2
+	}
+
+	if code := openDatabaseForCommand(*configPath); code != 0 {
+		return code
+	}
+
+	result, err := analysis.Simulate(*validatorId, *checkpoints, *scenario)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not run simulation: %v\n", err)
+		return 1
+	}
+
+	if *outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return 0
+	}
+
+	fmt.Printf("Validator %d, scenario %q, starting from checkpoint %d\n", result.ValidatorId, result.Scenario, result.LastCheckpoint)
+	fmt.Printf("Current performance %.4f, performance benchmark %.4f\n\n", result.Performance, result.PerformanceBenchmark)
+
+	// print around 20 evenly spaced steps, plus the one where the validator
+	// falls below the performance benchmark
+	fmt.Printf("%-12s %-22s %-10s %-12s %s\n", "Checkpoint", "Expected time", "PB", "Performance", "Below PB")
+	every := (len(result.Steps) + 19) / 20
+	for i, step := range result.Steps {
+		if (i+1)%every != 0 && i != len(result.Steps)-1 && (result.FirstBelowPB == nil || step.Checkpoint != *result.FirstBelowPB) {
+			continue
+		}
+
+		expected := "-"
+		if step.ExpectedTimestamp > 0 {
+			expected = time.Unix(step.ExpectedTimestamp, 0).UTC().Format("2006-01-02 15:04 MST")
+		}
+		fmt.Printf("%-12d %-22s %-10.4f %-12.4f %t\n", step.Checkpoint, expected, step.PerformanceBenchmark, step.Performance, step.BelowPB)
+	}
+
+	fmt.Println()
+	if result.FirstBelowPB == nil {
+		fmt.Printf("The validator stays above the performance benchmark for the next %d checkpoints.\n", len(result.Steps))
+	} else if result.SecondsToPB != nil {
+		fmt.Printf("The validator falls below the performance benchmark at checkpoint %d, in %d checkpoints (about %s).\n", *result.FirstBelowPB, *result.CheckpointsToPB, (time.Duration(*result.SecondsToPB) * time.Second).Round(time.Minute))
+	} else {
+		fmt.Printf("The validator falls below the performance benchmark at checkpoint %d, in %d checkpoints.\n", *result.FirstBelowPB, *result.CheckpointsToPB)
+	}
+
+	return 0
+}
+
+// backtestCommand runs the backtest subcommand with the passed arguments, and
+// returns the exit code. It recomputes the performance benchmark of a range of
+// checkpoints from the stored signer data, optionally with a different window
+// or factor, and saves the results in the performance_benchmark_backtest table
+// unless --dry-run is passed. The live values are never changed.
+func backtestCommand(args []string) int {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.json", "Path to config file")
+	from := flags.Int("from", 0, "First checkpoint to recompute (defaults to the first checkpoint in the database)")
+	to := flags.Int("to", 0, "Last checkpoint to recompute (defaults to the last checkpoint in the database)")
+	window := flags.Int("window", utils.PB_WINDOW, "Number of checkpoints the performance is calculated over")
+	factor := flags.Float64("factor", utils.PB_FACTOR, "Factor the median performance is multiplied by")
+	dryRun := flags.Bool("dry-run", false, "Do not save the results in the database")
+	outputJSON := flags.Bool("json", false, "Output every result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if code := openDatabaseForCommand(*configPath); code != 0 {
+		return code
+	}
+
+	var err error
+	if *from == 0 {
+		*from, err = database.GetFirstCheckpointNumber(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not get the first checkpoint: %v\n", err)
+			return 2
+		}
+	}
+	if *to == 0 {
+		*to, err = database.GetLastCheckpointNumber()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not get the last checkpoint: %v\n", err)
+			return 2
+		}
+	}
+
+	results, skipped, err := analysis.Backtest(*from, *to, *window, *factor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not run backtest: %v\n", err)
+		return 2
+	}
+
+	if !*dryRun && len(results) > 0 {
+		err = database.SaveBacktestResults(results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not save backtest results: %v\n", err)
+			return 2
+		}
+	}
+
+	if *outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+		return 0
+	}
+
+	fmt.Printf("Recomputed the performance benchmark of checkpoints %d to %d, over %d checkpoints with a factor of %g\n\n", *from, *to, *window, *factor)
+
+	// print around 20 evenly spaced results
+	fmt.Printf("%-12s %-10s %-10s %-10s %-12s %s\n", "Checkpoint", "PB", "Live PB", "Change", "Below PB", "Complete")
+	every := (len(results) + 19) / 20
+	for i, result := range results {
+		if (i+1)%every != 0 && i != len(results)-1 {
+			continue
+		}
+
+		live, change := "-", "-"
+		if result.LivePerformanceBenchmark != nil {
+			live = fmt.Sprintf("%.4f", *result.LivePerformanceBenchmark)
+			change = fmt.Sprintf("%+.4f", result.PerformanceBenchmark-*result.LivePerformanceBenchmark)
+		}
+		fmt.Printf("%-12d %-10.4f %-10s %-10s %-12d %t\n", result.Checkpoint, result.PerformanceBenchmark, live, change, result.ValidatorsBelow, result.Complete)
+	}
+
+	// compare with the live values
+	compared, missingLive, incomplete := 0, 0, 0
+	totalChange, maxChange, maxChangeCheckpoint := 0.0, 0.0, 0
+	for _, result := range results {
+		if !result.Complete {
+			incomplete++
+		}
+		if result.LivePerformanceBenchmark == nil {
+			missingLive++
+			continue
+		}
+
+		compared++
+		change := math.Abs(result.PerformanceBenchmark - *result.LivePerformanceBenchmark)
+		totalChange += change
+		if change > maxChange {
+			maxChange, maxChangeCheckpoint = change, result.Checkpoint
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Recomputed %d checkpoints, skipped %d without the data for their whole window.\n", len(results), skipped)
+	if missingLive > 0 {
+		fmt.Printf("%d checkpoints have no live performance benchmark.\n", missingLive)
+	}
+	if compared > 0 {
+		fmt.Printf("Compared with the live values of %d checkpoints: average change %.4f, largest change %.4f at checkpoint %d.\n", compared, totalChange/float64(compared), maxChange, maxChangeCheckpoint)
+	}
+	if incomplete > 0 {
+		fmt.Printf("%d checkpoints might be missing the signers of untracked validators, as only tracked validators are kept once checkpoints leave the last 700. Track all validators with \"*\" to keep everyone's signer data.\n", incomplete)
+	}
+	if *dryRun {
+		fmt.Println("Results not saved, as --dry-run was passed.")
+	} else {
+		fmt.Println("Results saved in the performance_benchmark_backtest table.")
+	}
+
+	return 0
+}
+
+// verifyCommand runs the verify subcommand with the passed arguments, and
+// returns the exit code. It recomputes the root hash of the Bor blocks covered
+// by each stored checkpoint in the range, and compares it with the submitted
+// one.
+func verifyCommand(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	configPath := flags.String("config", "config/config.json", "Path to config file")
+	from := flags.Int("from", 0, "First checkpoint to verify (defaults to the last checkpoint in the database)")
+	to := flags.Int("to", 0, "Last checkpoint to verify (defaults to the last checkpoint in the database)")
+	outputJSON := flags.Bool("json", false, "Output every result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if code := openDatabaseForCommand(*configPath); code != 0 {
+		return code
+	}
+	if utils.GetConfig().BorRpcUrl == "" {
+		fmt.Fprintln(os.Stderr, "BorRpcUrl must be set in the config to verify checkpoints")
+		return 2
+	}
+
+	if *to == 0 {
+		last, err := database.GetLastCheckpointNumber()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not get the last checkpoint: %v\n", err)
+			return 2
+		}
+		*to = last
+	}
+	if *from == 0 {
+		*from = *to
+	}
+
+	results := []utils.CheckpointVerification{}
+	skipped := 0
+	for checkpointNumber := *from; checkpointNumber <= *to; checkpointNumber++ {
+		verification, err := analysis.VerifyCheckpoint(checkpointNumber)
+		if err != nil {
+			var notFound *utils.CheckpointNotFoundError
+			if errors.As(err, &notFound) {
+				skipped++
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Could not verify checkpoint %d: %v\n", checkpointNumber, err)
+			return 2
+		}
+
+		err = database.SaveCheckpointVerification(verification)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not save the verification of checkpoint %d: %v\n", checkpointNumber, err)
+			return 2
+		}
+		results = append(results, verification)
+	}
+
+	mismatches := 0
+	for _, verification := range results {
+		if !verification.Match {
+			mismatches++
+		}
+	}
+
+	if *outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+	} else {
+		for _, verification := range results {
+			if !verification.Match {
+				fmt.Printf("Checkpoint %d (Bor blocks %d to %d) does not match: submitted %s, computed %s\n", verification.CheckpointNumber,
+					verification.StartBlock, verification.EndBlock, verification.SubmittedRootHash, verification.ComputedRootHash)
+			}
+		}
+		fmt.Printf("Verified %d checkpoints, %d did not match. Skipped %d without stored contents.\n", len(results), mismatches, skipped)
+	}
+
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
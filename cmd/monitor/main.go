@@ -2,16 +2,40 @@ package main
 
 import (
 	"flag"
+	"os"
 )
 
 func main() {
 
+	// run a subcommand if one was passed
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			os.Exit(configCommand(os.Args[2:]))
+		case "counters":
+			os.Exit(countersCommand(os.Args[2:]))
+		case "simulate":
+			os.Exit(simulateCommand(os.Args[2:]))
+		case "backtest":
+			os.Exit(backtestCommand(os.Args[2:]))
+		case "verify":
+			os.Exit(verifyCommand(os.Args[2:]))
+		}
+	}
+
 	var configPath string
+	var logFormat string
+	var logLevel string
 	flag.StringVar(&configPath, "config", "config/config.json", "Path to config file")
+	flag.StringVar(&logFormat, "log-format", "", "Log output format (text or json), overrides the config")
+	flag.StringVar(&logLevel, "log-level", "", "Log level (debug, info, warn or error), overrides the config")
 
 	// parse the path to config
 	flag.Parse()
 
-	mainLoop(configPath)
+	err := mainLoop(configPath, logFormat, logLevel)
+	if err != nil {
+		os.Exit(1)
+	}
 
 }
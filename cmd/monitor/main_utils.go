@@ -1,17 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"math/big"
+	"monitor/internal/analysis"
+	"monitor/internal/api"
 	database "monitor/internal/db"
 	"monitor/internal/metrics"
+	"monitor/internal/telemetry"
 	"monitor/internal/utils"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
-
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // getStartingBlock determines what ETH block we should start checking for
@@ -21,27 +25,34 @@ func getStartingBlock() (uint64, error) {
 	// check if the database exists
 	dbExists, err := utils.CheckIfDBExists()
 	if os.IsNotExist(err) {
-		fmt.Println("WARN: Database file does not exist. A new database will be created.")
+		slog.Warn("Database file does not exist. A new database will be created")
 		err = database.CreateDatabase()
 		if err != nil {
 			return 0, err
 		}
 	} else if err != nil {
-		fmt.Printf("ERR: Error while checking for database file, error: %v\n", err)
+		slog.Error("Error while checking for database file", "error", err)
 		return 0, err
+	} else {
+		// make sure the database has all the tables used by this version
+		err = database.MigrateDatabase()
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	// check if we are continuing from the last block in the database
+	continueFromBlock := utils.GetConfig().ContinueFromBlock
 	startingBlock := uint64(0)
-	if utils.Config.ContinueFromBlock == 0 {
+	if continueFromBlock == 0 {
 		startingBlock, err = database.GetLastBlockNumber()
 		if err == sql.ErrNoRows {
-			fmt.Println("WARN: No checkpoints found in database. Starting from current block - 100.")
+			slog.Warn("No checkpoints found in database. Starting from current block - 100")
 		} else if err != nil {
 			return 0, err
 		}
 	} else {
-		startingBlock = uint64(utils.Config.ContinueFromBlock)
+		startingBlock = uint64(continueFromBlock)
 		if dbExists {
 			// get the last block in the database (the block in which the last
 			// processed checkpoint was submitted)
@@ -49,15 +60,15 @@ func getStartingBlock() (uint64, error) {
 			if err != nil {
 				switch {
 				case err == sql.ErrNoRows:
-					fmt.Printf("WARN: No checkpoints found in database. Starting from block provided in config (%d).\n", startingBlock)
+					slog.Warn("No checkpoints found in database. Starting from block provided in config", "block", startingBlock)
 					lastBlockNumber = 0
 				default:
-					fmt.Printf("ERR: Error while querying for last block in database, error: %v\n", err)
+					slog.Error("Error while querying for last block in database", "error", err)
 					return 0, err
 				}
 			} else {
-				fmt.Printf("WARN: The database provided is not new, and monitoring will resume from the last block in the database (%d) rather than the one specified in the config (%d).\n", lastBlockNumber, startingBlock)
-				fmt.Println("WARN: If you would like to start from the block number provided in the config, please delete or move the database file and restart the process.")
+				slog.Warn("The database provided is not new, and monitoring will resume from the last block in the database rather than the one specified in the config", "block", lastBlockNumber, "config_block", startingBlock)
+				slog.Warn("If you would like to start from the block number provided in the config, please delete or move the database file and restart the process")
 
 				// start from the last block we processed, to ensure that all information about the last processed checkpoint is consistent
 				// it could be that the process was terminated while inserting, for example, leading to incorrect data
@@ -75,7 +86,7 @@ func getStartingBlock() (uint64, error) {
 
 		startingBlock = currBlockNumber - 100
 	} else {
-		if utils.Config.ContinueFromBlock == 0 {
+		if continueFromBlock == 0 {
 			// if we are continuing, use the last block in the database
 			err := metrics.UpdateCheckpointsSignedMetrics()
 			if err != nil {
@@ -89,118 +100,182 @@ func getStartingBlock() (uint64, error) {
 
 // getNewEventsAndDecode is the main function that gets events between a given
 // range and processes them, calling other functions to update the database and
-// metrics. It returns an error in case something goes wrong.
-func getNewEventsAndDecode(startBlock uint64, endBlock uint64) error {
+// metrics. It stops between checkpoints if the passed context is cancelled, so
+// that a checkpoint is never left half processed. It returns the block from
+// which processing should continue, which is the block of the first checkpoint
+// not processed if it stops early, and an error in case something goes wrong.
+func getNewEventsAndDecode(ctx context.Context, startBlock uint64, endBlock uint64) (uint64, error) {
 	newHeaderBlockEvents, err := utils.DecodeEvents(startBlock, endBlock)
 	if err != nil {
 		switch err.(type) {
 		case *utils.NoLogsFoundError:
-			fmt.Printf("INFO: No checkpoints were found between blocks %d and %d.\n", startBlock, endBlock)
-			return nil
+			slog.Info("No checkpoints were found in block range", "start_block", startBlock, "end_block", endBlock)
+			return endBlock + 1, nil
 		default:
-			return err
+			return startBlock, err
 		}
 	}
 
 	if len(newHeaderBlockEvents) > 0 {
 		if len(newHeaderBlockEvents) == 1 {
-			fmt.Printf("INFO: Processing checkpoint %d.\n", newHeaderBlockEvents[0].HeaderBlockId.Int64())
+			slog.Info("Processing checkpoint", "checkpoint", newHeaderBlockEvents[0].HeaderBlockId.Uint64())
 		} else {
-			fmt.Printf("INFO: Processing checkpoints %d to %d.\n", newHeaderBlockEvents[0].HeaderBlockId.Int64(), newHeaderBlockEvents[len(newHeaderBlockEvents)-1].HeaderBlockId.Int64())
+			slog.Info("Processing checkpoints", "start_checkpoint", newHeaderBlockEvents[0].HeaderBlockId.Uint64(), "end_checkpoint", newHeaderBlockEvents[len(newHeaderBlockEvents)-1].HeaderBlockId.Uint64())
 		}
 	}
 
 	for i, newEvent := range newHeaderBlockEvents {
-		metrics.CurrentCheckpoint.Set(float64(newEvent.HeaderBlockId.Int64()))
-		data, sigs := []byte{}, [][3]*big.Int{}
-
-		// retry call in case of failure
-		for i := 0; i < utils.RETRIES; i++ {
-			data, sigs, err = utils.GetCheckpointSignatures(newEvent.TxHash)
-			if err != nil {
-				switch err.(type) {
-				case *utils.DialError, *utils.TxHashError, *utils.PendingTxError:
-					time.Sleep(time.Second * utils.RETRY_WAIT)
-					continue
-				default:
-					fmt.Printf("ERR: Error while trying to get checkpoint signatures from transaction %v, error: %v\n", newEvent.TxHash, err)
-					return err
-				}
-			} else {
-				break
-			}
-
-		}
-		if err != nil {
-			fmt.Printf("ERR: Error while trying to get checkpoint signatures from transaction %v, error: %v\n", newEvent.TxHash, err)
-			return err
-		}
-
-		signers, errCount := utils.SignersFromTXData(data, sigs)
-
-		// get validators at this point
-		err = database.UpdateValidatorsDB(newEvent.BlockNumber, newEvent.HeaderBlockId.Uint64())
-		if err != nil {
-			return err
+		// only stop in between checkpoints
+		if ctx.Err() != nil {
+			return newEvent.BlockNumber, ctx.Err()
 		}
 
-		blockTimestamp, err := utils.GetBlockTimestamp(newEvent.BlockNumber)
+		pb, err := processCheckpoint(newEvent)
 		if err != nil {
-			return err
-		}
+			// remove whatever was written for this checkpoint, so that it is
+			// processed from scratch on the next attempt
+			rollbackErr := database.DeleteCheckpoint(newEvent.HeaderBlockId.Uint64())
+			if rollbackErr != nil {
+				slog.Error("Could not roll back checkpoint", "checkpoint", newEvent.HeaderBlockId.Uint64(), "error", rollbackErr)
+			}
 
-		if errCount > 0 {
-			fmt.Printf("WARN: There were %d errors while processing checkpoint number %d. The list of validators that signed it might be incomplete.", errCount, newEvent.HeaderBlockId.Uint64())
+			// the running counters and totals may already include the
+			// checkpoint, so they no longer match what is stored
+			_, verifyErr := metrics.VerifyCounters(true)
+			if verifyErr != nil {
+				slog.Error("Could not recount the running counters", "error", verifyErr)
+			}
+			return newEvent.BlockNumber, err
 		}
 
-		err = database.InsertCheckpoint(newEvent, blockTimestamp)
-		if err != nil {
-			return err
-		}
+		// the blocks up to this checkpoint are processed
+		telemetry.SetSyncProgress(newEvent.BlockNumber+1, endBlock)
 
-		err = database.InsertValidatorsSignedCheckpoint(newEvent.HeaderBlockId.Uint64(), signers, false)
-		if err != nil {
-			return err
+		progress := fmt.Sprintf("%.2f%%", float64(i+1)/float64(len(newHeaderBlockEvents))*100)
+		if pb != 0 {
+			slog.Info("Processed checkpoint", "checkpoint", newEvent.HeaderBlockId.Uint64(), "block", newEvent.BlockNumber, "pb", pb, "progress", progress)
+		} else {
+			slog.Info("Processed checkpoint", "checkpoint", newEvent.HeaderBlockId.Uint64(), "block", newEvent.BlockNumber, "progress", progress)
 		}
+	}
 
-		err = database.InsertValidatorsSignedCheckpoint(newEvent.HeaderBlockId.Uint64(), signers, true)
-		if err != nil {
-			return err
-		}
+	return endBlock + 1, nil
+}
 
-		pb, err := calculateAndInsertPerformanceBenchmark700(newEvent.HeaderBlockId.Uint64(), newEvent.BlockNumber)
+// processCheckpoint fetches the signers of the checkpoint in the passed event
+// and updates the database and metrics with it. It returns the performance
+// benchmark as of this checkpoint, or 0 if it could not be calculated.
+func processCheckpoint(newEvent utils.NewHeaderBlockEvent) (float64, error) {
+	defer func(start time.Time) {
+		telemetry.CheckpointProcessingDuration.Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	metrics.CurrentCheckpoint.Set(float64(newEvent.HeaderBlockId.Int64()))
+	data, sigs := []byte{}, [][3]*big.Int{}
+	var err error
+
+	// retry call in case of failure
+	for i := 0; i < utils.RETRIES; i++ {
+		data, sigs, err = utils.GetCheckpointSignatures(newEvent.TxHash)
 		if err != nil {
 			switch err.(type) {
-			case *utils.CheckpointNotFoundError:
-				fmt.Printf("WARN: Could not calculate performance benchmark for checkpoint %d as we do not have enough data for the 700 checkpoints before it.\n", newEvent.HeaderBlockId.Uint64())
+			case *utils.DialError, *utils.TxHashError, *utils.PendingTxError:
+				time.Sleep(time.Second * utils.RETRY_WAIT)
+				continue
 			default:
-				return err
+				slog.Error("Error while trying to get checkpoint signatures from transaction", "checkpoint", newEvent.HeaderBlockId.Uint64(), "tx_hash", newEvent.TxHash, "error", err)
+				return 0, err
 			}
 		} else {
-			metrics.CurrentPerformanceBenchmark.Set(pb)
+			break
 		}
 
-		err = metrics.UpdateCheckpointsSignedMetrics()
+	}
+	if err != nil {
+		slog.Error("Error while trying to get checkpoint signatures from transaction", "checkpoint", newEvent.HeaderBlockId.Uint64(), "tx_hash", newEvent.TxHash, "error", err)
+		return 0, err
+	}
+
+	signers, errCount := utils.SignersFromTXData(data, sigs)
+	telemetry.RecordSignatureRecoveryErrors(errCount)
+
+	// get validators at this point
+	err = database.UpdateValidatorsDB(newEvent.BlockNumber, newEvent.HeaderBlockId.Uint64())
+	if err != nil {
+		return 0, err
+	}
+
+	blockTimestamp, err := utils.GetBlockTimestamp(newEvent.BlockNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	if errCount > 0 {
+		slog.Warn("There were errors while processing checkpoint. The list of validators that signed it might be incomplete", "checkpoint", newEvent.HeaderBlockId.Uint64(), "tx_hash", newEvent.TxHash, "errors", errCount)
+	}
+
+	err = database.InsertCheckpoint(newEvent, blockTimestamp)
+	if err != nil {
+		return 0, err
+	}
+
+	insertCheckpointCost(newEvent)
+	insertCheckpointData(newEvent, data)
+
+	err = database.InsertValidatorsSignedCheckpoint(newEvent.HeaderBlockId.Uint64(), signers, false)
+	if err != nil {
+		return 0, err
+	}
+
+	err = database.InsertValidatorsSignedCheckpoint(newEvent.HeaderBlockId.Uint64(), signers, true)
+	if err != nil {
+		return 0, err
+	}
+
+	// the rewards are not needed to track performance, so if they cannot be
+	// estimated, they are estimated again later with backfillRewards
+	err = estimateAndInsertRewards(int(newEvent.HeaderBlockId.Uint64()))
+	if err != nil {
+		slog.Warn("Could not estimate the rewards of checkpoint, retrying later", "checkpoint", newEvent.HeaderBlockId.Uint64(), "error", err)
+		err = database.AddPendingRewards(int(newEvent.HeaderBlockId.Uint64()))
 		if err != nil {
-			return err
+			slog.Warn("Could not save checkpoint to estimate its rewards later", "checkpoint", newEvent.HeaderBlockId.Uint64(), "error", err)
 		}
+	}
 
-		if pb != 0 {
-			fmt.Printf("INFO: Processed checkpoint %d (ETH Block %d) - PB: %.5f%% [%.2f%%]\n", newEvent.HeaderBlockId.Int64(), newEvent.BlockNumber, pb*100, float64(i+1)/float64(len(newHeaderBlockEvents))*100)
-		} else {
-			fmt.Printf("INFO: Processed checkpoint %d (ETH Block %d) [%.2f%%]\n", newEvent.HeaderBlockId.Int64(), newEvent.BlockNumber, float64(i+1)/float64(len(newHeaderBlockEvents))*100)
+	pb, err := calculateAndInsertPerformanceBenchmark700(newEvent.HeaderBlockId.Uint64())
+	if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			slog.Warn("Could not calculate performance benchmark for checkpoint as we do not have enough data for the 700 checkpoints before it", "checkpoint", newEvent.HeaderBlockId.Uint64())
+		default:
+			return 0, err
 		}
+	} else {
+		metrics.CurrentPerformanceBenchmark.Set(pb)
 	}
 
-	return nil
+	err = metrics.UpdateCheckpointsSignedMetrics()
+	if err != nil {
+		return 0, err
+	}
+
+	// observe the interval from the previous checkpoint, only now that the
+	// checkpoint is fully processed and cannot be rolled back
+	err = metrics.ObserveCheckpointInterval(int(newEvent.HeaderBlockId.Uint64()), blockTimestamp)
+	if err != nil {
+		slog.Warn("Could not observe the interval from the previous checkpoint", "checkpoint", newEvent.HeaderBlockId.Uint64(), "error", err)
+	}
+
+	return pb, nil
 }
 
 // calculateAndInsertPerformanceBenchmark700 calculates and inserts the
 // performance benchmark in the database for the given checkpoint number. It
 // returns the resulting performance benchmark.
-func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64, blockNumber uint64) (float64, error) {
+func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64) (float64, error) {
 	// check if checkpointNumber - 699 exists first
-	exists, err := database.CheckIfCheckpointExistsInTemp(checkpointNumber - 699)
+	exists, err := database.CheckIfCheckpointExistsInTemp(checkpointNumber - (utils.PB_WINDOW - 1))
 	if err != nil {
 		return 0, err
 	}
@@ -211,13 +286,13 @@ func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64, blockNum
 
 	// if exists, prune the temp table as we only use the last 700 checkpoints
 	// we're keeping the performance of 1 additional checkpoint, just in case
-	err = database.DeleteTempCheckpoints(checkpointNumber - 700)
+	err = database.DeleteTempCheckpoints(checkpointNumber - utils.PB_WINDOW)
 	if err != nil {
 		return 0, err
 	}
 
 	// get the performance of all the validators in the temp table
-	checkpointCount, validatorsPerformance, err := database.GetSignedCheckpointsCountPerValidator(int(checkpointNumber)-699, int(checkpointNumber))
+	checkpointCount, validatorsPerformance, err := database.GetSignedCheckpointsCountPerValidator(int(checkpointNumber)-(utils.PB_WINDOW-1), int(checkpointNumber))
 	if err != nil {
 		return 0, err
 	}
@@ -231,11 +306,12 @@ func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64, blockNum
 	medianPerformance := utils.Median(performance)
 
 	// calculate performance benchmark
-	performanceBenchmark := medianPerformance * float64(0.95)
+	performanceBenchmark := medianPerformance * utils.PB_FACTOR
 
-	// get list of validators below threshold
+	// get list of validators below threshold, along with the performance of
+	// the active set
 	validatorsBelowThreshold := []int{}
-	firstOutput := true
+	activePerformance := []float64{}
 	for validatorId, validatorPerformance := range validatorsPerformance {
 		performanceFloat := float64(validatorPerformance) / float64(checkpointCount)
 
@@ -244,21 +320,21 @@ func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64, blockNum
 			return 0, err
 		}
 
-		if val.DeactivationEpoch == 0 && val.ActivationEpoch <= checkpointNumber-699 {
+		if val.InPerformanceBenchmark(checkpointNumber) {
+			activePerformance = append(activePerformance, performanceFloat)
 			if performanceFloat < performanceBenchmark {
-				if firstOutput {
-					firstOutput = false
-					fmt.Printf("INFO: Validator(s) below PB threshold: ")
-				}
-				fmt.Printf("%d = %.5f\t", validatorId, performanceFloat*100)
+				slog.Info("Validator below PB threshold", "checkpoint", checkpointNumber, "validator_id", validatorId, "performance", performanceFloat)
 				validatorsBelowThreshold = append(validatorsBelowThreshold, validatorId)
 			}
 		}
 	}
 	if len(validatorsBelowThreshold) > 0 {
-		fmt.Println()
+		slog.Info("Validators below PB threshold", "checkpoint", checkpointNumber, "count", len(validatorsBelowThreshold), "pb", performanceBenchmark)
 	}
 
+	// update the metrics on the distribution of the performance of the set
+	metrics.UpdateDistributionMetrics(activePerformance, performanceBenchmark)
+
 	// insert the PB into the checkpoints table
 	err = database.InsertPerformanceBenchmark(performanceBenchmark, int(checkpointNumber))
 	if err != nil {
@@ -268,66 +344,533 @@ func calculateAndInsertPerformanceBenchmark700(checkpointNumber uint64, blockNum
 	return performanceBenchmark, nil
 }
 
-// mainLoop is the loop that calls other functions, constantly iterating over
-// new blocks and looking for new checkpoint events.
-func mainLoop(configPath string) {
+// insertCheckpointCost gets the cost of the transaction which submitted the
+// checkpoint in the passed event, and inserts it in the database. As the cost
+// is not needed to track performance, failures are only logged.
+func insertCheckpointCost(newEvent utils.NewHeaderBlockEvent) {
+	var cost utils.TransactionCost
+	var err error
+
+	// retry call in case of failure
+	for i := 0; i < utils.RETRIES; i++ {
+		cost, err = utils.GetTransactionCost(newEvent.TxHash)
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second * utils.RETRY_WAIT)
+	}
+	if err == nil {
+		err = database.InsertCheckpointCost(newEvent.HeaderBlockId.Uint64(), cost)
+	}
+	if err != nil {
+		slog.Warn("Could not get the cost of submitting checkpoint, so it is not stored", "checkpoint", newEvent.HeaderBlockId.Uint64(), "tx_hash", newEvent.TxHash, "error", err)
+	}
+}
 
-	utils.UpdateConfigPath(configPath)
+// insertCheckpointData decodes the contents of the checkpoint in the passed
+// event from the data passed to submitCheckpoint, and inserts them in the
+// database. As they are not needed to track performance, failures are only
+// logged.
+func insertCheckpointData(newEvent utils.NewHeaderBlockEvent, data []byte) {
+	checkpointData, err := utils.DecodeCheckpointData(data)
+	if err == nil {
+		if checkpointData.Proposer != newEvent.ProposerAddress {
+			slog.Warn("Proposer in checkpoint data does not match the one in the event", "checkpoint", newEvent.HeaderBlockId.Uint64(), "data_proposer", checkpointData.Proposer, "event_proposer", newEvent.ProposerAddress)
+		}
+		err = database.InsertCheckpointData(newEvent.HeaderBlockId.Uint64(), checkpointData)
+	}
+	if err != nil {
+		slog.Warn("Could not decode the contents of checkpoint, so they are not stored", "checkpoint", newEvent.HeaderBlockId.Uint64(), "tx_hash", newEvent.TxHash, "error", err)
+	}
+}
 
-	go func() {
-		// get the block number we are starting from
-		startingBlock, err := getStartingBlock()
+// verifyCheckpoint recomputes the root hash of the Bor blocks covered by the
+// given checkpoint and saves whether it matches the submitted one. A
+// CheckpointNotFoundError is returned if the contents of the checkpoint are
+// not stored.
+func verifyCheckpoint(checkpointNumber int) error {
+	verification, err := analysis.VerifyCheckpoint(checkpointNumber)
+	if err != nil {
+		return err
+	}
+
+	err = database.SaveCheckpointVerification(verification)
+	if err != nil {
+		return err
+	}
+
+	if !verification.Match {
+		slog.Error("Root hash of checkpoint does not match the Bor blocks it covers", "checkpoint", checkpointNumber, "bor_start_block", verification.StartBlock,
+			"bor_end_block", verification.EndBlock, "submitted", verification.SubmittedRootHash, "computed", verification.ComputedRootHash)
+	}
+
+	return nil
+}
+
+// verifyCheckpointsBatch verifies the next VERIFICATION_BATCH checkpoints
+// processed, so that processing checkpoints is never held up by the Bor RPC.
+// It continues from the checkpoint saved after the previous batch, or from the
+// last checkpoint processed if no batch was verified yet. Checkpoints whose
+// contents are not stored are skipped. On any other error, the batch stops,
+// and continues from the checkpoint which failed on the next call.
+func verifyCheckpointsBatch() error {
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	nextCheckpoint, found, err := database.GetVerificationScanCheckpoint()
+	if err != nil {
+		return err
+	}
+	if !found {
+		nextCheckpoint = lastCheckpoint
+	}
+	if nextCheckpoint > lastCheckpoint {
+		return nil
+	}
+
+	batchEnd := min(lastCheckpoint, nextCheckpoint+utils.VERIFICATION_BATCH-1)
+	for ; nextCheckpoint <= batchEnd; nextCheckpoint++ {
+		err = verifyCheckpoint(nextCheckpoint)
+		if _, ok := err.(*utils.CheckpointNotFoundError); ok {
+			err = nil
+		}
 		if err != nil {
-			os.Exit(1)
+			break
 		}
-		// update the current block number metric
-		metrics.CurrentBlockNumber.Set(float64(startingBlock))
+	}
 
-		// check if the validators table is empty
-		emptyTable, err := database.ValidatorTableEmpty()
+	saveErr := database.SaveVerificationScanCheckpoint(nextCheckpoint)
+	if saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not verify checkpoint %d: %w", nextCheckpoint, err)
+	}
+
+	return metrics.UpdateVerificationMetrics()
+}
+
+// estimateAndInsertRewards estimates the rewards earned by the tracked
+// validators for the given checkpoint number, and inserts them in the
+// database.
+func estimateAndInsertRewards(checkpointNumber int) error {
+	trackedIds, err := database.ResolveTrackedValidators()
+	if err != nil {
+		return err
+	}
+
+	rewards, err := analysis.EstimateCheckpointRewards(checkpointNumber, trackedIds)
+	if err != nil {
+		return err
+	}
+
+	return database.SaveEstimatedRewards(checkpointNumber, rewards)
+}
+
+// backfillRewards estimates the rewards of the checkpoints whose rewards could
+// not be estimated when they were processed. Checkpoints whose signers are no
+// longer in the temporary table cannot be estimated anymore, and are dropped.
+// The running reward totals are counted again if any rewards are added.
+func backfillRewards() error {
+	pending, err := database.GetPendingRewards()
+	if err != nil {
+		return err
+	}
+
+	backfilled := 0
+	for _, checkpointNumber := range pending {
+		exists, err := database.CheckIfCheckpointExistsInTemp(uint64(checkpointNumber))
 		if err != nil {
-			os.Exit(1)
+			return err
 		}
 
-		if emptyTable {
-			// if the table is empty, insert validators in it
-			err = database.UpdateValidatorsDB(startingBlock, 0)
+		if !exists {
+			slog.Warn("Signers of checkpoint are no longer stored, so its rewards cannot be estimated", "checkpoint", checkpointNumber)
+		} else {
+			err = estimateAndInsertRewards(checkpointNumber)
 			if err != nil {
-				os.Exit(1)
+				slog.Warn("Could not estimate the rewards of checkpoint, retrying later", "checkpoint", checkpointNumber, "error", err)
+				continue
 			}
+			backfilled++
 		}
 
-		// get the current block number, which would be the last block in which
-		// the tool will look for checkpoint events
-		endBlock, err := utils.GetCurrentBlockNumber()
+		err = database.DeletePendingRewards(checkpointNumber)
 		if err != nil {
-			os.Exit(1)
+			return err
 		}
+	}
 
-		for {
-			// call the function to process new events
-			err := getNewEventsAndDecode(startingBlock, endBlock)
-			if err != nil {
-				os.Exit(1)
+	if backfilled > 0 {
+		slog.Info("Estimated the rewards of checkpoints processed earlier", "checkpoints", backfilled)
+		metrics.ResetRunningTotals()
+		return metrics.UpdateCheckpointsSignedMetrics()
+	}
+
+	return nil
+}
+
+// rewindVerification moves the next checkpoint to verify back to the passed
+// checkpoint if it is past it, so that it is verified again.
+func rewindVerification(checkpointNumber int) error {
+	nextCheckpoint, found, err := database.GetVerificationScanCheckpoint()
+	if err != nil {
+		return err
+	}
+	if !found || nextCheckpoint <= checkpointNumber {
+		return nil
+	}
+
+	return database.SaveVerificationScanCheckpoint(checkpointNumber)
+}
+
+// scanFailedSubmissions looks for the checkpoint submissions which reverted
+// between the given blocks, inserts them in the database and updates the
+// respective metrics.
+func scanFailedSubmissions(startBlock uint64, endBlock uint64) error {
+	submissions, err := utils.FindFailedSubmissions(startBlock, endBlock)
+	if err != nil {
+		return err
+	}
+	if len(submissions) == 0 {
+		return nil
+	}
+
+	err = database.InsertFailedSubmissions(submissions)
+	if err != nil {
+		return err
+	}
+
+	return metrics.UpdateFailedSubmissionMetrics()
+}
+
+// scanFailedSubmissionsBatch looks for the checkpoint submissions which
+// reverted in the next FAILED_SUBMISSIONS_BATCH blocks, up to endBlock. It
+// continues from the block saved after the previous batch, or from firstBlock
+// if no batch was scanned yet, so that the scan keeps its own position apart
+// from the processing of checkpoints.
+func scanFailedSubmissionsBatch(firstBlock uint64, endBlock uint64) error {
+	nextBlock, found, err := database.GetFailedSubmissionsScanBlock()
+	if err != nil {
+		return err
+	}
+	if !found {
+		nextBlock = firstBlock
+	}
+	if nextBlock > endBlock {
+		return nil
+	}
+
+	batchEnd := min(endBlock, nextBlock+utils.FAILED_SUBMISSIONS_BATCH-1)
+	err = scanFailedSubmissions(nextBlock, batchEnd)
+	if err != nil {
+		return err
+	}
+
+	return database.SaveFailedSubmissionsScanBlock(batchEnd + 1)
+}
+
+// initialiseSync prepares the database and returns the block number from which
+// the monitor should start looking for checkpoints.
+func initialiseSync() (uint64, error) {
+	// get the block number we are starting from
+	startingBlock, err := getStartingBlock()
+	if err != nil {
+		return 0, err
+	}
+	// update the current block number metric
+	metrics.CurrentBlockNumber.Set(float64(startingBlock))
+
+	// check if the validators table is empty
+	emptyTable, err := database.ValidatorTableEmpty()
+	if err != nil {
+		return 0, err
+	}
+
+	if emptyTable {
+		// if the table is empty, insert validators in it
+		err = database.UpdateValidatorsDB(startingBlock, 0)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return startingBlock, nil
+}
+
+// syncOnce processes all the blocks from startingBlock up to the current block,
+// and returns the block number to start from in the next iteration.
+func syncOnce(ctx context.Context, startingBlock uint64) (uint64, error) {
+	// get the current block number, which would be the last block in which
+	// the tool will look for checkpoint events
+	endBlock, err := utils.GetCurrentBlockNumber()
+	if err != nil {
+		return startingBlock, err
+	}
+
+	// nothing new to process
+	telemetry.SetSyncProgress(startingBlock, endBlock)
+	if endBlock < startingBlock {
+		telemetry.SetSyncMode(telemetry.SYNC_MODE_FOLLOWING)
+		return startingBlock, nil
+	}
+
+	// processing more blocks than the readiness allows for means catching up
+	if endBlock-startingBlock+1 > uint64(utils.MaxHeadLag()) {
+		telemetry.SetSyncMode(telemetry.SYNC_MODE_CATCHING_UP)
+	} else {
+		telemetry.SetSyncMode(telemetry.SYNC_MODE_FOLLOWING)
+	}
+
+	// call the function to process new events, continuing from the first
+	// checkpoint not processed if it stops early
+	firstBlock := startingBlock
+	startingBlock, err = getNewEventsAndDecode(ctx, startingBlock, endBlock)
+	metrics.CurrentBlockNumber.Set(float64(startingBlock))
+	if err != nil {
+		return startingBlock, err
+	}
+
+	// the next iteration starts from the block after the current one
+	telemetry.SetSyncProgress(startingBlock, endBlock)
+	telemetry.SetSyncMode(telemetry.SYNC_MODE_FOLLOWING)
+
+	// estimate the rewards which could not be estimated while processing the
+	// checkpoints. They are simply retried on the next iteration if it fails
+	err = backfillRewards()
+	if err != nil {
+		slog.Warn("Could not estimate the rewards of earlier checkpoints, retrying on the next iteration", "error", err)
+	}
+
+	// look for checkpoint submissions which reverted, one batch at a time so
+	// that processing checkpoints is never held up by it. It keeps its own
+	// position, so it is simply retried on the next iteration if it fails
+	if utils.GetConfig().ScanFailedSubmissions {
+		err = scanFailedSubmissionsBatch(firstBlock, endBlock)
+		if err != nil {
+			slog.Warn("Could not scan for failed checkpoint submissions, retrying on the next iteration", "error", err)
+		}
+	}
+
+	// verify the root hash of the checkpoints processed, one batch at a time
+	// for the same reason. It also keeps its own position
+	if utils.GetConfig().BorRpcUrl != "" {
+		err = verifyCheckpointsBatch()
+		if err != nil {
+			slog.Warn("Could not verify checkpoints, retrying on the next iteration", "error", err)
+		}
+	}
+
+	// poll the ETH balance of the tracked signers, which does not affect the
+	// rest of the metrics if it fails
+	err = metrics.UpdateBalanceMetrics(false)
+	if err != nil {
+		slog.Warn("Could not update the ETH balance of the tracked signers", "error", err)
+	}
+
+	return startingBlock, nil
+}
+
+// waitContext waits for the passed duration, returning early if the context
+// is cancelled, a config reload is requested or an admin command is received.
+// The config is reloaded right away, while the admin command is returned to be
+// run by the caller. It returns false if the context was cancelled.
+func waitContext(ctx context.Context, duration time.Duration, reloader *configReloader, admin *adminController) (bool, *api.AdminCommand) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, nil
+	case <-timer.C:
+		return true, nil
+	case <-reloader.requests:
+		reloader.reload()
+		return true, nil
+	case command := <-admin.commands:
+		return true, &command
+	}
+}
+
+// superviseSync keeps the monitor in sync with the chain until the context is
+// cancelled. Failures are retried with an exponential backoff, during which
+// the last known metrics remain available but are flagged as stale. Config
+// reloads are applied in between iterations, while admin commands also stop
+// the current iteration at the next checkpoint, and are run before syncing
+// continues.
+func superviseSync(ctx context.Context, reloader *configReloader, admin *adminController) {
+	var startingBlock uint64
+	initialised := false
+	backoff := time.Second * utils.RETRY_WAIT
+
+	for {
+		// only admin commands and config reloads are handled while paused
+		if admin.paused {
+			ok, command := waitContext(ctx, time.Second*utils.LOOP_INTERVAL, reloader, admin)
+			if !ok {
+				return
 			}
+			if command != nil {
+				admin.run(ctx, *command, startingBlock)
+			}
+			continue
+		}
 
-			// increment the block number for the next iteration
-			startingBlock = endBlock + 1
-			metrics.CurrentBlockNumber.Set(float64(startingBlock))
+		var err error
+		var command *api.AdminCommand
+		if !initialised {
+			startingBlock, err = initialiseSync()
+			initialised = err == nil
+		}
 
-			// sleep for a minute
-			time.Sleep(1 * time.Minute)
+		if err == nil {
+			syncCtx, stopWaiting := admin.interruptible(ctx)
+			startingBlock, err = syncOnce(syncCtx, startingBlock)
+			command = stopWaiting()
+		}
 
-			// get the latest block number again
-			endBlock, err = utils.GetCurrentBlockNumber()
+		if ctx.Err() != nil {
+			slog.Info("Stopped processing checkpoints")
+			return
+		}
+
+		// run the admin command received while syncing. If it stopped the
+		// sync early, carry on where it stopped right away
+		if command != nil {
+			admin.run(ctx, *command, startingBlock)
 			if err != nil {
-				os.Exit(1)
+				continue
 			}
+		}
 
+		// keep the time since the last checkpoint current, even when
+		// syncing fails. It is only updated between syncs, so that old
+		// checkpoints seen while catching up are not reported as a stall
+		if initialised {
+			if stallErr := metrics.UpdateStallMetrics(); stallErr != nil {
+				slog.Warn("Could not update the time since the last checkpoint", "error", stallErr)
+			}
 		}
+
+		if err != nil {
+			metrics.SetMetricsStale(true)
+			telemetry.RecordSyncError(err)
+			slog.Error("Error while processing checkpoints", "block", startingBlock, "retry_in", backoff.String(), "error", err)
+
+			ok, command := waitContext(ctx, backoff, reloader, admin)
+			if !ok {
+				return
+			}
+			if command != nil {
+				admin.run(ctx, *command, startingBlock)
+			}
+
+			// double the wait for the next failure, up to the maximum
+			backoff *= 2
+			if backoff > time.Second*utils.MAX_BACKOFF {
+				backoff = time.Second * utils.MAX_BACKOFF
+			}
+			continue
+		}
+
+		metrics.SetMetricsStale(false)
+		telemetry.RecordSyncSuccess()
+		backoff = time.Second * utils.RETRY_WAIT
+
+		// sleep for a minute
+		ok, command := waitContext(ctx, time.Second*utils.LOOP_INTERVAL, reloader, admin)
+		if !ok {
+			return
+		}
+		if command != nil {
+			admin.run(ctx, *command, startingBlock)
+		}
+	}
+}
+
+// mainLoop is the loop that calls other functions, constantly iterating over
+// new blocks and looking for new checkpoint events. It runs until SIGINT or
+// SIGTERM is received, after which it shuts down gracefully. The passed log
+// format and level take priority over the ones in the config, if not empty.
+func mainLoop(configPath string, logFormat string, logLevel string) error {
+
+	// use the default log settings until the config is loaded, so that even
+	// the first log lines have secrets redacted
+	utils.SetupLogger("", "")
+
+	utils.UpdateConfigPath(configPath)
+
+	config := utils.GetConfig()
+	configLogFormat, configLogLevel := logFormat, logLevel
+	if configLogFormat == "" {
+		configLogFormat = config.LogFormat
+	}
+	if configLogLevel == "" {
+		configLogLevel = config.LogLevel
+	}
+
+	err := utils.SetupLogger(configLogFormat, configLogLevel)
+	if err != nil {
+		slog.Error("Could not set up logger", "error", err)
+		return err
+	}
+
+	// cancel the context on SIGINT or SIGTERM
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(signalCtx)
+	defer cancel()
+
+	// publish metrics and the API on the configured address
+	admin := newAdminController()
+	server, err := api.NewServer(admin.commands)
+	if err != nil {
+		slog.Error("Could not set up metrics server", "error", err)
+		return err
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			serverErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	// reload the config when the file changes or on SIGHUP
+	reloader := newConfigReloader(logFormat, logLevel)
+	go reloader.watch(ctx)
+
+	syncDone := make(chan struct{})
+	go func() {
+		superviseSync(ctx, reloader, admin)
+		telemetry.SetSyncMode(telemetry.SYNC_MODE_STOPPED)
+		close(syncDone)
 	}()
 
-	// publish metrics on the configured port
-	http.Handle("/metrics", promhttp.Handler())
-	http.ListenAndServe(":"+utils.Config.PrometheusPort, nil)
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for the checkpoint in progress to finish")
+	case err = <-serverErr:
+		slog.Error("Metrics server stopped unexpectedly", "error", err)
+	}
+
+	// restore the default signal behaviour, so that a second signal forces an
+	// immediate exit
+	stop()
+	cancel()
+	<-syncDone
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second*utils.RETRY_WAIT)
+	defer shutdownCancel()
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		slog.Error("Error while shutting down metrics server", "error", shutdownErr)
+	}
+
+	return err
 }
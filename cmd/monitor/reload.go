@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"monitor/internal/api"
+	"monitor/internal/metrics"
+	"monitor/internal/utils"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloader watches the config file and SIGHUP, and reloads the config
+// when either of them fires. The reload itself is only done when requested by
+// the sync loop, so that the config never changes while a checkpoint is being
+// processed.
+type configReloader struct {
+	// log settings passed as flags, which take priority over the config
+	logFormat string
+	logLevel  string
+
+	// requests receives a value whenever the config should be reloaded
+	requests chan struct{}
+
+	// reloaded receives a value whenever the config was reloaded, so that the
+	// watched files can be updated
+	reloaded chan struct{}
+}
+
+// newConfigReloader returns a configReloader which respects the passed log
+// settings flags.
+func newConfigReloader(logFormat string, logLevel string) *configReloader {
+	return &configReloader{
+		logFormat: logFormat,
+		logLevel:  logLevel,
+		requests:  make(chan struct{}, 1),
+		reloaded:  make(chan struct{}, 1),
+	}
+}
+
+// request queues a reload, unless one is already pending.
+func (r *configReloader) request() {
+	select {
+	case r.requests <- struct{}{}:
+	default:
+	}
+}
+
+// watch requests a reload whenever the config file changes or SIGHUP is
+// received, until the context is cancelled.
+func (r *configReloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("Could not watch config file, reload it by sending SIGHUP instead", "error", err)
+	} else {
+		defer watcher.Close()
+	}
+
+	// watch the directories rather than the files, as editors usually replace
+	// the file rather than writing to it. The files can change on reload, so
+	// the directories watched are brought in line with them after each one.
+	watchedFiles := []string{}
+	watchedDirs := map[string]bool{}
+	syncWatcher := func() {
+		watchedFiles = filesToWatch()
+		if watcher == nil {
+			return
+		}
+
+		dirs := map[string]bool{}
+		for _, path := range watchedFiles {
+			dirs[filepath.Dir(path)] = true
+		}
+		for dir := range watchedDirs {
+			if !dirs[dir] {
+				watcher.Remove(dir)
+				delete(watchedDirs, dir)
+			}
+		}
+		for dir := range dirs {
+			if watchedDirs[dir] {
+				continue
+			}
+			err := watcher.Add(dir)
+			if err != nil {
+				slog.Error("Could not watch config file, reload it by sending SIGHUP instead", "path", dir, "error", err)
+			} else {
+				watchedDirs[dir] = true
+				events = watcher.Events
+			}
+		}
+	}
+	syncWatcher()
+
+	// changes are usually written in bursts, so wait for them to settle
+	debounce := time.NewTimer(0)
+	<-debounce.C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading config")
+			r.request()
+		case event := <-events:
+			if isWatchedFile(event.Name, watchedFiles) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(time.Second)
+			}
+		case <-debounce.C:
+			slog.Info("Config file changed, reloading config", "path", utils.ConfigPath())
+			r.request()
+		case <-r.reloaded:
+			syncWatcher()
+		}
+	}
+}
+
+// filesToWatch returns the files whose changes trigger a reload: the config
+// file, along with the validator metadata file and the TLS certificate if set,
+// so that renewed certificates are picked up.
+func filesToWatch() []string {
+	config := utils.GetConfig()
+	files := []string{utils.ConfigPath()}
+	if config.ValidatorMetadataFile != "" {
+		files = append(files, config.ValidatorMetadataFile)
+	}
+	if config.TLSCertFile != "" {
+		files = append(files, config.TLSCertFile, config.TLSKeyFile)
+	}
+	return files
+}
+
+// reload loads and validates the config file, and applies it if it is valid.
+// It cleans up the metrics of validators that are no longer tracked, and
+// reloads the TLS certificate.
+func (r *configReloader) reload() {
+	oldConfig, err := utils.ReloadConfig()
+	if err != nil {
+		slog.Error("New config is not valid, keeping the current one", "path", utils.ConfigPath(), "error", err)
+		return
+	}
+	config := utils.GetConfig()
+
+	// update the logger, unless its settings were passed as flags
+	logFormat, logLevel := r.logFormat, r.logLevel
+	if logFormat == "" {
+		logFormat = config.LogFormat
+	}
+	if logLevel == "" {
+		logLevel = config.LogLevel
+	}
+	err = utils.SetupLogger(logFormat, logLevel)
+	if err != nil {
+		slog.Error("Could not update logger", "error", err)
+	}
+
+	// keep serving the current certificate if the new one cannot be loaded
+	err = api.ReloadCertificate()
+	if err != nil {
+		slog.Error("Could not reload TLS certificate, keeping the current one", "error", err)
+	}
+
+	validatorsChanged := !utils.SameKeys(oldConfig.PublicKeys, config.PublicKeys) || !reflect.DeepEqual(oldConfig.Validators, config.Validators) || !reflect.DeepEqual(oldConfig.ValidatorMetadata, config.ValidatorMetadata)
+	windowsChanged := !utils.SameKeys(oldConfig.PerformanceWindows, config.PerformanceWindows)
+	if validatorsChanged || windowsChanged {
+		if validatorsChanged {
+			slog.Info("Tracked validators or their metadata changed. Newly tracked validators only have data from this point onwards", "public_keys", config.PublicKeys, "validators", config.Validators)
+		}
+		if windowsChanged {
+			slog.Info("Performance windows changed", "windows", config.PerformanceWindows)
+		}
+
+		// drop the series of all validators and repopulate them with the ones
+		// that are still tracked
+		metrics.ResetValidatorMetrics()
+		err = metrics.UpdateCheckpointsSignedMetrics()
+		if err != nil {
+			slog.Error("Error while updating metrics after config reload", "error", err)
+		}
+	}
+
+	// the files to watch may have changed with the config
+	select {
+	case r.reloaded <- struct{}{}:
+	default:
+	}
+
+	slog.Info("Config reloaded", "path", utils.ConfigPath())
+}
+
+// isWatchedFile returns true if the passed path is one of the watched files.
+func isWatchedFile(path string, watchedFiles []string) bool {
+	for _, watchedFile := range watchedFiles {
+		if filepath.Clean(path) == filepath.Clean(watchedFile) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"fmt"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// Backtest recomputes the performance benchmark of every checkpoint within the
+// range provided, from the stored signer data, using the passed window size
+// and factor instead of utils.PB_WINDOW and utils.PB_FACTOR. The performance
+// benchmark is the median performance of the validators which signed at least
+// one checkpoint in the window, times the factor, and it applies to the
+// validators which were active for the whole window. As when processing
+// checkpoints, a checkpoint is only included if the first checkpoint of its
+// window is in the database. The second return value is the number of
+// checkpoints in the range which were skipped.
+func Backtest(startNumber int, endNumber int, window int, factor float64) ([]utils.BacktestResult, int, error) {
+	if window < 1 {
+		return nil, 0, fmt.Errorf("the window must be at least 1 checkpoint")
+	}
+	if factor <= 0 || factor > 1 {
+		return nil, 0, fmt.Errorf("the factor must be greater than 0 and at most 1")
+	}
+	if startNumber > endNumber {
+		return nil, 0, fmt.Errorf("the start of the range must not be after its end")
+	}
+
+	signers, err := database.GetSignersPerCheckpoint(startNumber-window+1, endNumber)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	livePerformanceBenchmarks, err := database.GetPerformanceBenchmarksInRange(startNumber, endNumber)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the signers of all validators are only kept for the checkpoints in the
+	// temporary table, unless all validators are tracked
+	trackAll := utils.CheckIfTrackAll()
+	firstTempCheckpoint, err := database.GetFirstCheckpointNumber(true)
+	if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			firstTempCheckpoint = endNumber + 1
+		default:
+			return nil, 0, err
+		}
+	}
+
+	validators := map[int]utils.Validator{}
+	results := []utils.BacktestResult{}
+	skipped := 0
+
+	// slide the window along the range, adding the signers of the checkpoint
+	// entering the window and removing the ones of the checkpoint leaving it
+	signed := map[int]int{}
+	checkpointCount := 0
+	for number := startNumber - window + 1; number <= endNumber; number++ {
+		if checkpointSigners, found := signers[number]; found {
+			checkpointCount++
+			for _, validatorId := range checkpointSigners {
+				signed[validatorId]++
+			}
+		}
+		if checkpointSigners, found := signers[number-window]; found {
+			checkpointCount--
+			for _, validatorId := range checkpointSigners {
+				signed[validatorId]--
+				if signed[validatorId] == 0 {
+					delete(signed, validatorId)
+				}
+			}
+		}
+
+		if number < startNumber {
+			continue
+		}
+
+		windowStart := number - window + 1
+		if _, found := signers[number]; !found {
+			skipped++
+			continue
+		}
+		if _, found := signers[windowStart]; !found {
+			skipped++
+			continue
+		}
+
+		performance := map[int]float64{}
+		values := []float64{}
+		for validatorId, count := range signed {
+			performance[validatorId] = float64(count) / float64(checkpointCount)
+			values = append(values, performance[validatorId])
+		}
+
+		median := utils.Median(values)
+		result := utils.BacktestResult{
+			Checkpoint:           number,
+			Window:               window,
+			Factor:               factor,
+			PerformanceBenchmark: median * factor,
+			Median:               median,
+			Validators:           len(values),
+			Complete:             trackAll || windowStart >= firstTempCheckpoint,
+		}
+		if livePerformanceBenchmark, found := livePerformanceBenchmarks[number]; found {
+			result.LivePerformanceBenchmark = &livePerformanceBenchmark
+		}
+
+		// count the validators below the performance benchmark, out of the
+		// ones it applies to
+		for validatorId, value := range performance {
+			validator, found := validators[validatorId]
+			if !found {
+				validator, err = database.GetValidator(validatorId)
+				if err != nil {
+					return nil, 0, err
+				}
+				validators[validatorId] = validator
+			}
+
+			if validator.ActiveForWindow(uint64(number), window) && value < result.PerformanceBenchmark {
+				result.ValidatorsBelow++
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, skipped, nil
+}
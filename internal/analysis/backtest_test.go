@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// useBacktestDatabase creates a new database for the rest of the test, holding
+// checkpoints 1 to 10. Validators 1 and 4 signed all of them, validator 2
+// every third one, and validator 3, which was activated at checkpoint 7, the
+// even ones from checkpoint 8 on.
+func useBacktestDatabase(t *testing.T) {
+	previous := utils.GetConfig()
+	utils.SetConfig(utils.GeneralSettings{DatabaseLocation: filepath.Join(t.TempDir(), "test.db"), PublicKeys: []string{"*"}})
+	t.Cleanup(func() { utils.SetConfig(*previous) })
+
+	err := database.CreateDatabase()
+	if err != nil {
+		t.Fatalf("CreateDatabase() returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO validators(id, owner_key, signer_key, activation_epoch, deactivation_epoch) VALUES
+			(1, 'owner1', 'signer1', 1, 0),
+			(2, 'owner2', 'signer2', 1, 0),
+			(3, 'owner3', 'signer3', 7, 0),
+			(4, 'owner4', 'signer4', 1, 0)`)
+	if err != nil {
+		t.Fatalf("could not insert validators: %v", err)
+	}
+
+	for number := 1; number <= 10; number++ {
+		_, err = db.Exec(`INSERT INTO checkpoints(id, number, block_number, timestamp, proposer_id, reward) VALUES (?, ?, ?, ?, 1, 0)`,
+			number, number, 1000+number, 1700000000+10*number)
+		if err != nil {
+			t.Fatalf("could not insert checkpoint %d: %v", number, err)
+		}
+
+		signers := []int{1, 4}
+		if number%3 == 0 {
+			signers = append(signers, 2)
+		}
+		if number >= 8 && number%2 == 0 {
+			signers = append(signers, 3)
+		}
+		for _, validatorId := range signers {
+			_, err = db.Exec(`INSERT INTO validators_signed_checkpoints(checkpoint_id, validator_id) VALUES (?, ?)`, number, validatorId)
+			if err != nil {
+				t.Fatalf("could not insert signer of checkpoint %d: %v", number, err)
+			}
+		}
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	useBacktestDatabase(t)
+
+	tests := []struct {
+		name       string
+		checkpoint int
+		window     int
+		factor     float64
+		skipped    bool
+		pb         float64
+		validators int
+		below      int
+	}{
+		// validators 2 and 3 signed one of 6 to 8, but validator 3 was not
+		// active for the whole window
+		{"new validator not counted", 8, 3, 0.95, false, 0.95 * 2 / 3, 4, 1},
+		// validator 3 was active for all of 8 to 10, and signed two of them
+		{"new validator counted", 10, 3, 0.95, false, 0.95 * 5 / 6, 4, 2},
+		{"lower factor", 8, 3, 0.3, false, 0.3 * 2 / 3, 4, 0},
+		// validator 3 signed none of 1 to 5, so it is not in the median
+		{"whole set", 5, 5, 0.95, false, 0.95, 3, 1},
+		{"window before the first checkpoint", 5, 6, 0.95, true, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, skipped, err := Backtest(test.checkpoint, test.checkpoint, test.window, test.factor)
+			if err != nil {
+				t.Fatalf("Backtest() returned error: %v", err)
+			}
+			if test.skipped {
+				if skipped != 1 || len(results) != 0 {
+					t.Errorf("Backtest() = %+v, skipped %d, expected the checkpoint to be skipped", results, skipped)
+				}
+				return
+			}
+			if skipped != 0 || len(results) != 1 {
+				t.Fatalf("Backtest() = %+v, skipped %d, expected one result", results, skipped)
+			}
+
+			result := results[0]
+			if math.Abs(result.PerformanceBenchmark-test.pb) > 1e-9 || result.Validators != test.validators || result.ValidatorsBelow != test.below {
+				t.Errorf("Backtest() = pb %v, %d validators, %d below, expected pb %v, %d validators, %d below",
+					result.PerformanceBenchmark, result.Validators, result.ValidatorsBelow, test.pb, test.validators, test.below)
+			}
+		})
+	}
+}
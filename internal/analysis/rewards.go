@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"log/slog"
+	"math/big"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// EstimateCheckpointRewards estimates the rewards earned by each of the passed
+// validators for the passed checkpoint, based on its reward, its signers and
+// the stake of each validator at the block it was included in. The proposer
+// gets PROPOSER_BONUS percent of the reward, and the rest is shared between
+// the signers in proportion to their stake. The part of a validator's reward
+// earned by its delegated stake goes to its delegators, minus the validator's
+// commission, and is also estimated per POL delegated. For the validators
+// which were active but did not sign the checkpoint, the reward they would
+// have earned had they signed is estimated instead. It must be called while
+// the signers of every validator are still in the temporary table. Nothing is
+// returned if the checkpoint's reward is not known.
+func EstimateCheckpointRewards(checkpointNumber int, validatorIds []int) ([]utils.EstimatedReward, error) {
+	blockNumber, proposerId, reward, err := database.GetCheckpointReward(checkpointNumber)
+	if err != nil {
+		return nil, err
+	}
+	if reward == nil || len(validatorIds) == 0 {
+		return []utils.EstimatedReward{}, nil
+	}
+
+	signers, err := database.GetSignersPerCheckpoint(checkpointNumber, checkpointNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	stakes, err := database.GetValidatorStakesAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// add up the stake which signed the checkpoint
+	signedStake := new(big.Int)
+	signed := map[int]bool{}
+	for _, validatorId := range signers[checkpointNumber] {
+		signed[validatorId] = true
+		if stake, found := stakes[validatorId]; found {
+			signedStake.Add(signedStake, stake.Stake)
+		} else {
+			slog.Debug("Stake of signer not known, so it is not counted when estimating rewards", "checkpoint", checkpointNumber, "validator_id", validatorId)
+		}
+	}
+
+	proposerBonus := new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(utils.PROPOSER_BONUS)), big.NewInt(100))
+	signersReward := new(big.Int).Sub(reward, proposerBonus)
+
+	results := []utils.EstimatedReward{}
+	for _, validatorId := range validatorIds {
+		stake, found := stakes[validatorId]
+		if !found || stake.Stake.Sign() == 0 {
+			continue
+		}
+
+		estimate := utils.NewEstimatedReward(validatorId)
+		if signed[validatorId] {
+			if signedStake.Sign() > 0 {
+				estimate.Reward.Div(new(big.Int).Mul(signersReward, stake.Stake), signedStake)
+			}
+			if validatorId == proposerId {
+				estimate.Reward.Add(estimate.Reward, proposerBonus)
+			}
+
+			// the delegators' part, minus the validator's commission
+			estimate.DelegatorReward.Mul(estimate.Reward, stake.DelegatedStake)
+			estimate.DelegatorReward.Mul(estimate.DelegatorReward, big.NewInt(int64(100-min(stake.CommissionRate, 100))))
+			estimate.DelegatorReward.Div(estimate.DelegatorReward, new(big.Int).Mul(stake.Stake, big.NewInt(100)))
+
+			// and what each POL (1e18 wei) delegated earned from it
+			if stake.DelegatedStake.Sign() > 0 {
+				estimate.DelegatorRewardPerPOL.Div(new(big.Int).Mul(estimate.DelegatorReward, big.NewInt(1e18)), stake.DelegatedStake)
+			}
+		} else {
+			validator, err := database.GetValidator(validatorId)
+			if err != nil {
+				return nil, err
+			}
+			if !activeAt(validator, uint64(checkpointNumber)) {
+				continue
+			}
+
+			// had the validator signed, its stake would have been counted
+			// in the stake which signed
+			estimate.MissedReward.Div(new(big.Int).Mul(signersReward, stake.Stake), new(big.Int).Add(signedStake, stake.Stake))
+		}
+
+		results = append(results, estimate)
+	}
+
+	return results, nil
+}
+
+// activeAt returns true if the passed validator was part of the validator set
+// at the passed checkpoint.
+func activeAt(validator utils.Validator, checkpointNumber uint64) bool {
+	return validator.ActivationEpoch <= checkpointNumber && (validator.DeactivationEpoch == 0 || validator.DeactivationEpoch > checkpointNumber)
+}
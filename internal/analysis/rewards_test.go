@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"database/sql"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// useRewardsDatabase creates a new database for the rest of the test, holding
+// checkpoint 5 with a reward of 1000 wei proposed by validator 1 and signed by
+// validators 1 and 2, and checkpoint 6 whose reward is not known.
+func useRewardsDatabase(t *testing.T) {
+	previous := utils.GetConfig()
+	utils.SetConfig(utils.GeneralSettings{DatabaseLocation: filepath.Join(t.TempDir(), "test.db")})
+	t.Cleanup(func() { utils.SetConfig(*previous) })
+
+	err := database.CreateDatabase()
+	if err != nil {
+		t.Fatalf("CreateDatabase() returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	fixtures := []string{
+		// validator 4 was deactivated before checkpoint 5, and validator 5
+		// has no stake
+		`INSERT INTO validators(id, owner_key, signer_key, activation_epoch, deactivation_epoch) VALUES
+			(1, 'owner1', 'signer1', 1, 0),
+			(2, 'owner2', 'signer2', 1, 0),
+			(3, 'owner3', 'signer3', 1, 0),
+			(4, 'owner4', 'signer4', 1, 5),
+			(5, 'owner5', 'signer5', 1, 0)`,
+		`INSERT INTO checkpoints(id, number, block_number, timestamp, proposer_id, reward, reward_wei) VALUES
+			(1, 5, 1000, 1700000000, 1, 0, '1000'),
+			(2, 6, 1100, 1700000100, 2, 0, NULL)`,
+		`INSERT INTO temp_validators_signed_checkpoints(checkpoint_id, validator_id) VALUES
+			(1, 1), (1, 2), (2, 1), (2, 2)`,
+		// the stake of validator 1 changed after checkpoint 5
+		`INSERT INTO validator_stake_history(validator_id, block_number, stake, delegated_stake, commission_rate) VALUES
+			(1, 900, '600', '300', 10),
+			(1, 1050, '900', '600', 10),
+			(2, 900, '300', '0', 0),
+			(3, 900, '300', '150', 0),
+			(4, 900, '300', '0', 0)`,
+	}
+	for _, fixture := range fixtures {
+		_, err = db.Exec(fixture)
+		if err != nil {
+			t.Fatalf("could not insert fixture: %v", err)
+		}
+	}
+}
+
+func TestEstimateCheckpointRewards(t *testing.T) {
+	useRewardsDatabase(t)
+
+	// of the 1000 wei, the proposer gets a bonus of 100, and the remaining 900
+	// are shared by the 900 stake which signed
+	tests := []struct {
+		name                  string
+		validatorId           int
+		found                 bool
+		reward                int64
+		delegatorReward       int64
+		delegatorRewardPerPOL string
+		missedReward          int64
+	}{
+		// 600 for its stake plus the bonus, of which 300/600 was delegated,
+		// minus 10% commission
+		{"proposer", 1, true, 700, 315, "1050000000000000000", 0},
+		{"signer", 2, true, 300, 0, "0", 0},
+		// would have shared the 900 with 1200 stake
+		{"active non-signer", 3, true, 0, 0, "0", 225},
+		{"deactivated", 4, false, 0, 0, "0", 0},
+		{"no stake", 5, false, 0, 0, "0", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			estimates, err := EstimateCheckpointRewards(5, []int{test.validatorId})
+			if err != nil {
+				t.Fatalf("EstimateCheckpointRewards() returned error: %v", err)
+			}
+			if !test.found {
+				if len(estimates) != 0 {
+					t.Errorf("EstimateCheckpointRewards() = %+v, expected no estimate", estimates)
+				}
+				return
+			}
+			if len(estimates) != 1 {
+				t.Fatalf("EstimateCheckpointRewards() = %+v, expected one estimate", estimates)
+			}
+
+			estimate := estimates[0]
+			delegatorRewardPerPOL, _ := new(big.Int).SetString(test.delegatorRewardPerPOL, 10)
+			if estimate.ValidatorId != test.validatorId ||
+				estimate.Reward.Cmp(big.NewInt(test.reward)) != 0 ||
+				estimate.DelegatorReward.Cmp(big.NewInt(test.delegatorReward)) != 0 ||
+				estimate.DelegatorRewardPerPOL.Cmp(delegatorRewardPerPOL) != 0 ||
+				estimate.MissedReward.Cmp(big.NewInt(test.missedReward)) != 0 {
+				t.Errorf("EstimateCheckpointRewards() = {%d %s %s %s %s}, expected {%d %d %d %s %d}",
+					estimate.ValidatorId, estimate.Reward, estimate.DelegatorReward, estimate.DelegatorRewardPerPOL, estimate.MissedReward,
+					test.validatorId, test.reward, test.delegatorReward, test.delegatorRewardPerPOL, test.missedReward)
+			}
+		})
+	}
+}
+
+func TestEstimateCheckpointRewardsUnknown(t *testing.T) {
+	useRewardsDatabase(t)
+
+	tests := []struct {
+		name             string
+		checkpointNumber int
+		validatorIds     []int
+		notFound         bool
+	}{
+		{"reward not known", 6, []int{1, 2}, false},
+		{"no validators", 5, []int{}, false},
+		{"checkpoint not found", 7, []int{1, 2}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			estimates, err := EstimateCheckpointRewards(test.checkpointNumber, test.validatorIds)
+			if test.notFound {
+				if _, ok := err.(*utils.CheckpointNotFoundError); !ok {
+					t.Errorf("EstimateCheckpointRewards() returned error %v, expected CheckpointNotFoundError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EstimateCheckpointRewards() returned error: %v", err)
+			}
+			if len(estimates) != 0 {
+				t.Errorf("EstimateCheckpointRewards() = %+v, expected no estimates", estimates)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// the scenarios supported by the simulator
+const (
+	// SCENARIO_MISS assumes the validator misses every checkpoint, while the
+	// rest of the set keeps its current signing rate.
+	SCENARIO_MISS = "miss"
+	// SCENARIO_CURRENT assumes the whole set, including the validator, keeps
+	// its current signing rate.
+	SCENARIO_CURRENT = "current"
+	// SCENARIO_SIGN assumes the validator signs every checkpoint, while the
+	// rest of the set keeps its current signing rate.
+	SCENARIO_SIGN = "sign"
+)
+
+// MAX_SIMULATION_CHECKPOINTS is the maximum number of checkpoints that can be
+// simulated. After 700 checkpoints, none of the real data is left in the
+// window, so projecting further is of little use.
+const MAX_SIMULATION_CHECKPOINTS = 2100
+
+// SimulationStep is the projected state after a number of checkpoints.
+type SimulationStep struct {
+	Checkpoint           int     `json:"checkpoint"`
+	ExpectedTimestamp    int64   `json:"expected_timestamp,omitempty"`
+	PerformanceBenchmark float64 `json:"performance_benchmark"`
+	Performance          float64 `json:"performance"`
+	BelowPB              bool    `json:"below_performance_benchmark"`
+}
+
+// SimulationResult is the result of a simulation. FirstBelowPB is the first
+// checkpoint at which the validator is projected to be below the performance
+// benchmark, if any, with CheckpointsToPB and SecondsToPB being how far that is
+// from the last checkpoint processed.
+type SimulationResult struct {
+	ValidatorId          int              `json:"validator_id"`
+	Scenario             string           `json:"scenario"`
+	LastCheckpoint       int              `json:"last_checkpoint"`
+	PerformanceBenchmark float64          `json:"performance_benchmark"`
+	Performance          float64          `json:"performance"`
+	FirstBelowPB         *int             `json:"first_below_performance_benchmark"`
+	CheckpointsToPB      *int             `json:"checkpoints_to_performance_benchmark"`
+	SecondsToPB          *float64         `json:"seconds_to_performance_benchmark"`
+	Steps                []SimulationStep `json:"steps"`
+}
+
+// Simulate projects the performance benchmark and the performance of the
+// passed validator forward by the passed number of checkpoints, under the
+// passed scenario. It starts from the real signer data of the last 700
+// checkpoints in the database. As checkpoints leave the window, they are
+// replaced by projected ones, in which each validator signs according to its
+// signing rate over the last 700 checkpoints (or always or never, for the
+// validator being simulated, depending on the scenario). Rates are applied as
+// expected values, so the projection is deterministic.
+func Simulate(validatorId int, checkpoints int, scenario string) (SimulationResult, error) {
+	if scenario != SCENARIO_MISS && scenario != SCENARIO_CURRENT && scenario != SCENARIO_SIGN {
+		return SimulationResult{}, fmt.Errorf("unknown scenario %q, expected %s, %s or %s", scenario, SCENARIO_MISS, SCENARIO_CURRENT, SCENARIO_SIGN)
+	}
+	if checkpoints < 1 || checkpoints > MAX_SIMULATION_CHECKPOINTS {
+		return SimulationResult{}, fmt.Errorf("the number of checkpoints must be between 1 and %d", MAX_SIMULATION_CHECKPOINTS)
+	}
+
+	_, err := database.GetValidator(validatorId)
+	if err != nil {
+		return SimulationResult{}, &utils.ValidatorNotFoundError{GenericError: utils.GenericError{Message: fmt.Sprintf("validator %d not found", validatorId)}}
+	}
+
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// get the real signer data of the last 700 checkpoints
+	windowStart := lastCheckpoint - (utils.PB_WINDOW - 1)
+	signedCheckpoints, err := database.GetSignedCheckpointNumbersPerValidator(windowStart, lastCheckpoint)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// get the numbers of the checkpoints we have within the window
+	checkpointNumbers := []int{}
+	seen := map[int]bool{}
+	for _, numbers := range signedCheckpoints {
+		for _, number := range numbers {
+			if !seen[number] {
+				seen[number] = true
+				checkpointNumbers = append(checkpointNumbers, number)
+			}
+		}
+	}
+	sort.Ints(checkpointNumbers)
+
+	if len(checkpointNumbers) == 0 {
+		return SimulationResult{}, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "no signer data found for the last 700 checkpoints"}}
+	}
+
+	// calculate the signing rate of each validator
+	rates := map[int]float64{}
+	for id, numbers := range signedCheckpoints {
+		rates[id] = float64(len(numbers)) / float64(len(checkpointNumbers))
+	}
+	switch scenario {
+	case SCENARIO_MISS:
+		rates[validatorId] = 0
+	case SCENARIO_SIGN:
+		rates[validatorId] = 1
+	}
+
+	// get the checkpoint cadence, to estimate when each checkpoint happens
+	interval, err := database.GetAverageCheckpointInterval(lastCheckpoint-utils.CADENCE_CHECKPOINTS, lastCheckpoint)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	lastTimestamp, err := database.GetCheckpointTimestamp(lastCheckpoint)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	result := SimulationResult{ValidatorId: validatorId, Scenario: scenario, LastCheckpoint: lastCheckpoint, Steps: []SimulationStep{}}
+	result.PerformanceBenchmark, result.Performance = projectStep(0, windowStart, signedCheckpoints, checkpointNumbers, rates, validatorId)
+
+	for step := 1; step <= checkpoints; step++ {
+		pb, performance := projectStep(step, windowStart, signedCheckpoints, checkpointNumbers, rates, validatorId)
+
+		simulationStep := SimulationStep{
+			Checkpoint:           lastCheckpoint + step,
+			PerformanceBenchmark: pb,
+			Performance:          performance,
+			BelowPB:              performance < pb,
+		}
+		if interval > 0 {
+			simulationStep.ExpectedTimestamp = int64(float64(lastTimestamp) + float64(step)*interval)
+		}
+		result.Steps = append(result.Steps, simulationStep)
+
+		if simulationStep.BelowPB && result.FirstBelowPB == nil {
+			checkpoint, checkpointsToPB := simulationStep.Checkpoint, step
+			result.FirstBelowPB, result.CheckpointsToPB = &checkpoint, &checkpointsToPB
+			if interval > 0 {
+				secondsToPB := float64(step) * interval
+				result.SecondsToPB = &secondsToPB
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// projectStep returns the projected performance benchmark and performance of
+// the passed validator, after the passed number of checkpoints. The window
+// then covers the real checkpoints which are still within the last 700, plus
+// the projected ones.
+func projectStep(step int, windowStart int, signedCheckpoints map[int][]int, checkpointNumbers []int, rates map[int]float64, validatorId int) (float64, float64) {
+	start := windowStart + step
+
+	// the real checkpoints still within the window, and the projected ones
+	realCheckpoints := len(checkpointNumbers) - sort.SearchInts(checkpointNumbers, start)
+	projectedCheckpoints := step
+	if projectedCheckpoints > utils.PB_WINDOW {
+		projectedCheckpoints = utils.PB_WINDOW
+	}
+	checkpointCount := float64(realCheckpoints + projectedCheckpoints)
+
+	performance := []float64{}
+	validatorPerformance := 0.0
+	for id, rate := range rates {
+		numbers := signedCheckpoints[id]
+		signed := float64(len(numbers)-sort.SearchInts(numbers, start)) + rate*float64(projectedCheckpoints)
+
+		// validators which signed none of the checkpoints in the window are
+		// not counted in the performance benchmark
+		if signed > 0 {
+			performance = append(performance, signed/checkpointCount)
+		}
+		if id == validatorId {
+			validatorPerformance = signed / checkpointCount
+		}
+	}
+
+	return utils.Median(performance) * utils.PB_FACTOR, validatorPerformance
+}
@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectStep(t *testing.T) {
+	// the window starts at checkpoint 1, but only checkpoints 1 to 4 are
+	// known. Validator 1 signed all of them, validator 2 the even ones and
+	// validator 3 the first three.
+	signedCheckpoints := map[int][]int{1: {1, 2, 3, 4}, 2: {2, 4}, 3: {1, 2, 3}}
+	checkpointNumbers := []int{1, 2, 3, 4}
+
+	tests := []struct {
+		name                string
+		step                int
+		rate                float64
+		expectedPB          float64
+		expectedPerformance float64
+	}{
+		{"current, no projection", 0, 0.5, 0.75 * 0.95, 0.5},
+		// checkpoints 3 and 4 are left, plus two projected ones
+		{"current, half projected", 2, 0.5, 0.625 * 0.95, 0.5},
+		{"miss, half projected", 2, 0, 0.625 * 0.95, 0.25},
+		// a validator which signed nothing is not counted in the benchmark
+		{"miss, all projected", 4, 0, 0.875 * 0.95, 0},
+		{"sign, all projected", 4, 1, 0.95, 1},
+		// no more than a full window is projected
+		{"current, past a full window", 800, 0.5, 0.75 * 0.95, 0.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rates := map[int]float64{1: 1, 2: test.rate, 3: 0.75}
+
+			pb, performance := projectStep(test.step, 1, signedCheckpoints, checkpointNumbers, rates, 2)
+			if math.Abs(pb-test.expectedPB) > 1e-9 || math.Abs(performance-test.expectedPerformance) > 1e-9 {
+				t.Errorf("projectStep(%d) = %v, %v, expected %v, %v", test.step, pb, performance, test.expectedPB, test.expectedPerformance)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package analysis
+
+import (
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// VerifyCheckpoint recomputes the root hash of the Bor blocks covered by the
+// passed checkpoint, using the Bor RPC in the config, and compares it with
+// the root hash submitted in the checkpoint. The contents of the checkpoint
+// must be stored, otherwise a CheckpointNotFoundError is returned. The result
+// is not saved.
+func VerifyCheckpoint(checkpointNumber int) (utils.CheckpointVerification, error) {
+	data, err := database.GetCheckpointData(checkpointNumber)
+	if err != nil {
+		return utils.CheckpointVerification{}, err
+	}
+
+	rootHash, err := utils.GetBorRootHash(data.StartBlock, data.EndBlock)
+	if err != nil {
+		return utils.CheckpointVerification{}, err
+	}
+
+	return utils.CheckpointVerification{
+		CheckpointNumber:  checkpointNumber,
+		StartBlock:        data.StartBlock,
+		EndBlock:          data.EndBlock,
+		SubmittedRootHash: data.RootHash,
+		ComputedRootHash:  rootHash,
+		Match:             rootHash == data.RootHash,
+	}, nil
+}
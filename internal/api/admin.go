@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"monitor/internal/utils"
+)
+
+// the actions which can be requested through the admin API
+const (
+	ADMIN_PAUSE                 = "pause"
+	ADMIN_RESUME                = "resume"
+	ADMIN_RESCAN                = "rescan"
+	ADMIN_VALIDATORS            = "validators"
+	ADMIN_PERFORMANCE_BENCHMARK = "performance-benchmark"
+	ADMIN_METRICS               = "metrics"
+)
+
+// AdminCommand is an action requested through the admin API, to be run by the
+// sync loop in between checkpoints. StartBlock and EndBlock are only set for
+// rescans, and Block for validator updates, where 0 means the last block
+// processed. The result is sent on Result once the command is done.
+type AdminCommand struct {
+	Action     string
+	StartBlock uint64
+	EndBlock   uint64
+	Block      uint64
+	Result     chan AdminResult
+}
+
+// AdminResult is the outcome of an admin command. Err is nil on success.
+type AdminResult struct {
+	Message string
+	Err     error
+}
+
+// adminResponse is the body returned by the admin API on success.
+type adminResponse struct {
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// Admin returns the handler of the admin API, which passes the requested
+// command on to the sync loop through the passed channel and waits for its
+// result. Commands are sent as POST requests to /admin/<action>, and must be
+// authenticated with the AdminToken in the config as a bearer token. The API
+// is disabled if no token is set.
+func Admin(commands chan<- AdminCommand) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminToken := utils.GetConfig().AdminToken
+		if adminToken == "" {
+			writeError(w, http.StatusNotFound, "admin API is disabled, set AdminToken to enable it")
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || !secretsEqual(token, adminToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		command, err := parseAdminCommand(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// the sync loop only picks up commands in between checkpoints
+		select {
+		case commands <- command:
+		case <-r.Context().Done():
+			return
+		}
+
+		// the command keeps running if the client stops waiting for it
+		var result AdminResult
+		select {
+		case result = <-command.Result:
+		case <-r.Context().Done():
+			return
+		}
+
+		var commandErr *utils.AdminCommandError
+		var checkpointNotFound *utils.CheckpointNotFoundError
+		switch {
+		case errors.As(result.Err, &commandErr) || errors.As(result.Err, &checkpointNotFound):
+			writeError(w, http.StatusConflict, result.Err.Error())
+			return
+		case result.Err != nil:
+			writeError(w, http.StatusInternalServerError, result.Err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, adminResponse{Action: command.Action, Message: result.Message})
+	}
+}
+
+// parseAdminCommand returns the command requested by the passed request, based
+// on its path and query parameters.
+func parseAdminCommand(r *http.Request) (AdminCommand, error) {
+	query := r.URL.Query()
+	command := AdminCommand{
+		Action: strings.TrimPrefix(r.URL.Path, "/admin/"),
+		Result: make(chan AdminResult, 1),
+	}
+
+	switch command.Action {
+	case ADMIN_PAUSE, ADMIN_RESUME, ADMIN_PERFORMANCE_BENCHMARK, ADMIN_METRICS:
+	case ADMIN_RESCAN:
+		startBlock, err := strconv.ParseUint(query.Get("from"), 10, 64)
+		if err != nil {
+			return AdminCommand{}, errors.New("from must be a block number")
+		}
+		endBlock, err := strconv.ParseUint(query.Get("to"), 10, 64)
+		if err != nil || endBlock < startBlock {
+			return AdminCommand{}, errors.New("to must be a block number, not lower than from")
+		}
+		command.StartBlock, command.EndBlock = startBlock, endBlock
+	case ADMIN_VALIDATORS:
+		if value := query.Get("block"); value != "" {
+			block, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || block == 0 {
+				return AdminCommand{}, errors.New("block must be a block number")
+			}
+			command.Block = block
+		}
+	default:
+		return AdminCommand{}, errors.New("unknown action, expected pause, resume, rescan, validators, performance-benchmark or metrics")
+	}
+
+	return command, nil
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"monitor/internal/utils"
+)
+
+// errorResponse is the body returned by the API on error.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes the passed value as the JSON body of the response, with the
+// passed status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		slog.Error("Error while writing API response", "error", err)
+	}
+}
+
+// writeError writes an error response with the passed status code. Secrets
+// are redacted from the message, as it may contain details from the RPC.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: utils.RedactSecrets(message)})
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	database "monitor/internal/db"
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+)
+
+// healthResponse is the body returned by the health endpoint.
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessCheck is the result of a single check of the readiness endpoint.
+type readinessCheck struct {
+	Ok      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// readinessResponse is the body returned by the readiness endpoint.
+type readinessResponse struct {
+	Ready  bool                      `json:"ready"`
+	Checks map[string]readinessCheck `json:"checks"`
+}
+
+// rpcStatus is the state of the requests sent to an RPC endpoint, as returned
+// by the status endpoint.
+type rpcStatus struct {
+	Requests    int    `json:"requests"`
+	Errors      int    `json:"errors"`
+	LastSuccess *int64 `json:"last_success"`
+	LastErrorAt *int64 `json:"last_error_at"`
+	LastFailed  bool   `json:"last_failed"`
+}
+
+// statusResponse is the body returned by the status endpoint. Timestamps are
+// unix timestamps, and null if the respective event has not happened yet.
+type statusResponse struct {
+	Mode                    string               `json:"mode"`
+	NextBlock               uint64               `json:"next_block"`
+	HeadBlock               uint64               `json:"head_block"`
+	HeadBlockLag            uint64               `json:"head_block_lag"`
+	LastCheckpoint          *int                 `json:"last_checkpoint"`
+	LastCheckpointTimestamp *uint64              `json:"last_checkpoint_timestamp"`
+	PerformanceBenchmark    *float64             `json:"performance_benchmark"`
+	StartedAt               int64                `json:"started_at"`
+	LastSuccessfulLoop      *int64               `json:"last_successful_loop"`
+	LastError               string               `json:"last_error,omitempty"`
+	LastErrorAt             *int64               `json:"last_error_at"`
+	ConsecutiveErrors       int                  `json:"consecutive_errors"`
+	TotalErrors             int                  `json:"total_errors"`
+	SignatureErrors         int                  `json:"signature_recovery_errors"`
+	RPC                     map[string]rpcStatus `json:"rpc"`
+}
+
+// Healthz reports that the process is alive and serving requests. It does not
+// check anything else, so that a slow RPC or database does not get the
+// process restarted.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readyz reports whether the monitor is ready, being that the database can be
+// queried, the sync loop is running, not paused and has not failed repeatedly,
+// the last request to the ETH RPC succeeded, and the monitor is within
+// MaxHeadLag blocks of the head of the chain. It responds with 503 if any check
+// fails.
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	state := telemetry.GetSyncState()
+	checks := map[string]readinessCheck{}
+
+	checks["database"] = readinessCheck{Ok: true}
+	if err := database.CheckDatabase(); err != nil {
+		checks["database"] = readinessCheck{Message: utils.RedactSecrets(err.Error())}
+	}
+
+	switch {
+	case state.Mode == telemetry.SYNC_MODE_STARTING:
+		checks["sync"] = readinessCheck{Message: "sync has not started yet"}
+	case state.Mode == telemetry.SYNC_MODE_PAUSED:
+		checks["sync"] = readinessCheck{Message: "sync is paused"}
+	case state.Mode == telemetry.SYNC_MODE_STOPPED:
+		checks["sync"] = readinessCheck{Message: "sync has stopped"}
+	case state.ConsecutiveErrors >= utils.RETRIES:
+		checks["sync"] = readinessCheck{Message: utils.RedactSecrets(state.LastError)}
+	default:
+		checks["sync"] = readinessCheck{Ok: true}
+	}
+
+	ethState := state.RPC[telemetry.ENDPOINT_ETH]
+	switch {
+	case ethState.Requests == 0:
+		checks["rpc"] = readinessCheck{Message: "no requests sent to the ETH RPC yet"}
+	case ethState.LastFailed:
+		checks["rpc"] = readinessCheck{Message: "last request to the ETH RPC failed"}
+	default:
+		checks["rpc"] = readinessCheck{Ok: true}
+	}
+
+	checks["lag"] = readinessCheck{Ok: true}
+	if state.HeadBlockLag > uint64(utils.MaxHeadLag()) {
+		checks["lag"] = readinessCheck{Message: "more than MaxHeadLag blocks behind the head of the chain"}
+	}
+
+	response := readinessResponse{Ready: true, Checks: checks}
+	for _, check := range checks {
+		response.Ready = response.Ready && check.Ok
+	}
+
+	status := http.StatusOK
+	if !response.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
+
+// Status returns the state of the sync loop, along with the last checkpoint
+// processed and the performance benchmark as of it.
+func Status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	state := telemetry.GetSyncState()
+	response := statusResponse{
+		Mode:               state.Mode,
+		NextBlock:          state.NextBlock,
+		HeadBlock:          state.HeadBlock,
+		HeadBlockLag:       state.HeadBlockLag,
+		StartedAt:          state.StartedAt.Unix(),
+		LastSuccessfulLoop: unixOrNil(state.LastSuccessfulLoop),
+		LastError:          utils.RedactSecrets(state.LastError),
+		LastErrorAt:        unixOrNil(state.LastErrorAt),
+		ConsecutiveErrors:  state.ConsecutiveErrors,
+		TotalErrors:        state.TotalErrors,
+		SignatureErrors:    state.SignatureErrors,
+		RPC:                map[string]rpcStatus{},
+	}
+	for endpoint, rpcState := range state.RPC {
+		response.RPC[endpoint] = rpcStatus{
+			Requests:    rpcState.Requests,
+			Errors:      rpcState.Errors,
+			LastSuccess: unixOrNil(rpcState.LastSuccess),
+			LastErrorAt: unixOrNil(rpcState.LastErrorAt),
+			LastFailed:  rpcState.LastFailed,
+		}
+	}
+
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == nil {
+		response.LastCheckpoint = &lastCheckpoint
+
+		timestamp, err := database.GetCheckpointTimestamp(lastCheckpoint)
+		if err == nil {
+			response.LastCheckpointTimestamp = &timestamp
+		}
+
+		pb, err := database.GetPBAtCheckpoint(lastCheckpoint)
+		if err == nil {
+			response.PerformanceBenchmark = &pb
+		} else if err != sql.ErrNoRows {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else if err != sql.ErrNoRows {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// unixOrNil returns the unix timestamp of the passed time, or nil if it is
+// the zero time.
+func unixOrNil(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	timestamp := t.Unix()
+	return &timestamp
+}
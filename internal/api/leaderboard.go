@@ -0,0 +1,127 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// leaderboardEntry is the position of a single validator in the leaderboard.
+type leaderboardEntry struct {
+	Rank               int      `json:"rank"`
+	Percentile         float64  `json:"percentile"`
+	ValidatorId        int      `json:"validator_id"`
+	Signer             string   `json:"signer"`
+	Owner              string   `json:"owner"`
+	Name               string   `json:"name,omitempty"`
+	Team               string   `json:"team,omitempty"`
+	Groups             []string `json:"groups,omitempty"`
+	Tracked            bool     `json:"tracked"`
+	Signed             int      `json:"signed"`
+	Checkpoints        int      `json:"checkpoints"`
+	Performance        float64  `json:"performance"`
+	DistanceFromMedian float64  `json:"distance_from_median"`
+	BelowPB            bool     `json:"below_performance_benchmark"`
+}
+
+// leaderboardResponse is the body returned by the leaderboard endpoint.
+type leaderboardResponse struct {
+	Checkpoint           int                `json:"checkpoint"`
+	PerformanceBenchmark *float64           `json:"performance_benchmark"`
+	Median               float64            `json:"median"`
+	Validators           []leaderboardEntry `json:"validators"`
+}
+
+// Leaderboard lists the active validator set ordered by performance over the
+// last 700 checkpoints, best first. The number of validators listed can be
+// limited with the limit query parameter.
+func Leaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive whole number")
+			return
+		}
+	}
+
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusServiceUnavailable, "no checkpoints processed yet")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setPerformance, err := database.GetSetPerformance700(lastCheckpoint)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// read the tracked validators as last resolved by the sync loop, as this
+	// route must not write to the database
+	trackedIds, err := database.GetTrackedValidators()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := leaderboardResponse{Checkpoint: lastCheckpoint, Validators: []leaderboardEntry{}}
+
+	pb, err := database.GetPBAtCheckpoint(lastCheckpoint)
+	if err == nil {
+		response.PerformanceBenchmark = &pb
+	}
+
+	// rank the active set
+	performance := map[int]float64{}
+	validators := map[int]utils.ValidatorPerformance{}
+	values := []float64{}
+	for _, validatorPerformance := range setPerformance {
+		if validatorPerformance.Active {
+			performance[validatorPerformance.Validator.ValidatorId] = validatorPerformance.Performance
+			validators[validatorPerformance.Validator.ValidatorId] = validatorPerformance
+			values = append(values, validatorPerformance.Performance)
+		}
+	}
+	response.Median = utils.Median(values)
+
+	for _, rank := range utils.RankPerformance(performance) {
+		if limit > 0 && len(response.Validators) >= limit {
+			break
+		}
+
+		validatorPerformance := validators[rank.ValidatorId]
+		metadata := utils.ValidatorMetadata(validatorPerformance.Validator)
+
+		response.Validators = append(response.Validators, leaderboardEntry{
+			Rank:               rank.Rank,
+			Percentile:         rank.Percentile,
+			ValidatorId:        rank.ValidatorId,
+			Signer:             validatorPerformance.Validator.SignerAddress.String(),
+			Owner:              validatorPerformance.Validator.OwnerAddress.String(),
+			Name:               metadata.Name,
+			Team:               metadata.Team,
+			Groups:             metadata.Groups,
+			Tracked:            utils.Contains(trackedIds, rank.ValidatorId),
+			Signed:             validatorPerformance.Signed,
+			Checkpoints:        validatorPerformance.Checkpoints,
+			Performance:        rank.Performance,
+			DistanceFromMedian: rank.Performance - response.Median,
+			BelowPB:            response.PerformanceBenchmark != nil && rank.Performance < pb,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"monitor/internal/utils"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	certificateLock sync.RWMutex
+	certificate     *tls.Certificate
+)
+
+// NewServer returns the server of the metrics and the API, listening on the
+// address in the config. Its routes are split by access:
+//   - /healthz and /readyz are always public, so that probes keep working.
+//   - /metrics requires the credentials in the config, if any are set, unless
+//     PublicMetrics is true.
+//   - /leaderboard, /simulate and /status require the credentials in the
+//     config, if any are set.
+//   - /admin/ requires the AdminToken, and passes commands on to the sync
+//     loop through the passed channel.
+//
+// If a TLS certificate is set, it is loaded and the server must be started
+// with ListenAndServeTLS, with empty paths.
+func NewServer(adminCommands chan<- AdminCommand) (*http.Server, error) {
+	mux := http.NewServeMux()
+
+	metricsHandler := promhttp.Handler()
+	mux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetConfig().PublicMetrics {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
+		authenticated(metricsHandler).ServeHTTP(w, r)
+	}))
+
+	mux.HandleFunc("/healthz", Healthz)
+	mux.HandleFunc("/readyz", Readyz)
+	mux.Handle("/leaderboard", authenticated(http.HandlerFunc(Leaderboard)))
+	mux.Handle("/simulate", authenticated(http.HandlerFunc(Simulate)))
+	mux.Handle("/status", authenticated(http.HandlerFunc(Status)))
+	mux.Handle("/admin/", Admin(adminCommands))
+
+	server := &http.Server{Addr: utils.ListenAddr(), Handler: mux}
+
+	config := utils.GetConfig()
+	if config.TLSCertFile == "" {
+		if config.AuthUsername != "" || config.AuthToken != "" || config.AdminToken != "" {
+			slog.Warn("Credentials are sent in plain text, set TLSCertFile and TLSKeyFile to serve over TLS", "address", server.Addr)
+		}
+		return server, nil
+	}
+
+	err := ReloadCertificate()
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+	}
+
+	return server, nil
+}
+
+// ReloadCertificate loads the TLS certificate and key in the config, and
+// serves them to new connections from now on. If they cannot be loaded, the
+// current certificate is kept. It does nothing if TLS is not set up.
+func ReloadCertificate() error {
+	config := utils.GetConfig()
+	if config.TLSCertFile == "" {
+		return nil
+	}
+
+	loaded, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+
+	certificateLock.Lock()
+	defer certificateLock.Unlock()
+
+	certificate = &loaded
+	return nil
+}
+
+// getCertificate returns the TLS certificate currently loaded.
+func getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certificateLock.RLock()
+	defer certificateLock.RUnlock()
+
+	return certificate, nil
+}
+
+// authenticated wraps the passed handler, so that requests must carry either
+// the basic auth credentials or the bearer token in the config. Requests are
+// let through if neither is set.
+func authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// take the credentials from a single snapshot of the config, so that
+		// a reload cannot mix the old and new ones
+		config := utils.GetConfig()
+		username, password, token := config.AuthUsername, config.AuthPassword, config.AuthToken
+		if username == "" && token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if requestUsername, requestPassword, ok := r.BasicAuth(); ok && username != "" {
+			// compare both, so that the time taken does not tell which is wrong
+			usernameMatches := secretsEqual(requestUsername, username)
+			if secretsEqual(requestPassword, password) && usernameMatches {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if requestToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && token != "" {
+			if secretsEqual(requestToken, token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if username != "" {
+			w.Header().Add("WWW-Authenticate", `Basic realm="monitor"`)
+		}
+		if token != "" {
+			w.Header().Add("WWW-Authenticate", "Bearer")
+		}
+		writeError(w, http.StatusUnauthorized, "missing or invalid credentials")
+	})
+}
+
+// secretsEqual compares the passed value with a secret in constant time.
+func secretsEqual(value string, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(value), []byte(secret)) == 1
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"monitor/internal/analysis"
+	"monitor/internal/utils"
+)
+
+// Simulate projects the performance benchmark and the performance of a
+// validator forward, under the chosen scenario. It takes the validator,
+// checkpoints (700 by default) and scenario (miss by default) query
+// parameters.
+func Simulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+
+	validatorId, err := strconv.Atoi(query.Get("validator"))
+	if err != nil || validatorId <= 0 {
+		writeError(w, http.StatusBadRequest, "validator must be a validator ID")
+		return
+	}
+
+	checkpoints := utils.PB_WINDOW
+	if value := query.Get("checkpoints"); value != "" {
+		checkpoints, err = strconv.Atoi(value)
+		if err != nil || checkpoints < 1 || checkpoints > analysis.MAX_SIMULATION_CHECKPOINTS {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("checkpoints must be a whole number between 1 and %d", analysis.MAX_SIMULATION_CHECKPOINTS))
+			return
+		}
+	}
+
+	scenario := query.Get("scenario")
+	switch scenario {
+	case "":
+		scenario = analysis.SCENARIO_MISS
+	case analysis.SCENARIO_MISS, analysis.SCENARIO_CURRENT, analysis.SCENARIO_SIGN:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("scenario must be %s, %s or %s", analysis.SCENARIO_MISS, analysis.SCENARIO_CURRENT, analysis.SCENARIO_SIGN))
+		return
+	}
+
+	result, err := analysis.Simulate(validatorId, checkpoints, scenario)
+	var validatorNotFound *utils.ValidatorNotFoundError
+	var checkpointNotFound *utils.CheckpointNotFoundError
+	switch {
+	case errors.As(err, &validatorNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case err == sql.ErrNoRows || errors.As(err, &checkpointNotFound):
+		writeError(w, http.StatusServiceUnavailable, "no checkpoints processed yet")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -2,7 +2,7 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
+	"log/slog"
 	"monitor/internal/utils"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -11,14 +11,14 @@ import (
 // createDatabase creates the database that is used by the tool, in the location
 // defined in the config.
 func CreateDatabase() error {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
 
-	fmt.Println("INFO: Creating new database.")
+	slog.Info("Creating new database")
 
 	// create validators table
 	createValidatorsTableSQL := `CREATE TABLE IF NOT EXISTS validators (
@@ -31,7 +31,7 @@ func CreateDatabase() error {
 
 	_, err = db.Exec(createValidatorsTableSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while creating validators' table, error: %v\n", err)
+		slog.Error("Error while creating validators' table", "error", err)
 		return err
 	}
 
@@ -49,7 +49,7 @@ func CreateDatabase() error {
 
 	_, err = db.Exec(createCheckpointsTableSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while creating checkpoints' table, error: %v\n", err)
+		slog.Error("Error while creating checkpoints' table", "error", err)
 		return err
 	}
 
@@ -65,7 +65,7 @@ func CreateDatabase() error {
 
 	_, err = db.Exec(createValidatorsSignedCheckpointsTableSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while creating validators' signed checkpoints table, error: %v\n", err)
+		slog.Error("Error while creating validators' signed checkpoints table", "error", err)
 		return err
 	}
 
@@ -81,7 +81,309 @@ func CreateDatabase() error {
 
 	_, err = db.Exec(createTemporaryValidatorsSignedCheckpointsTableSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while creating temporary validators' signed checkpoints table, error: %v\n", err)
+		slog.Error("Error while creating temporary validators' signed checkpoints table", "error", err)
+		return err
+	}
+
+	return MigrateDatabase()
+}
+
+// MigrateDatabase brings a database created by an older version of the tool up
+// to date, by creating any table that was added since. It is safe to call on
+// every startup.
+func MigrateDatabase() error {
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	// create tracked validators table - used to remember which validator a
+	// tracked owner or signer key resolved to, in case the key changes
+	createTrackedValidatorsTableSQL := `CREATE TABLE IF NOT EXISTS tracked_validators (
+		"selector" TEXT NOT NULL,
+		"validator_id" INTEGER NOT NULL,
+		PRIMARY KEY(selector, validator_id),
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createTrackedValidatorsTableSQL)
+	if err != nil {
+		slog.Error("Error while creating tracked validators table", "error", err)
+		return err
+	}
+
+	// create window state table - holds the range of checkpoints covered by
+	// the running counters of each performance window
+	createWindowStateTableSQL := `CREATE TABLE IF NOT EXISTS window_state (
+		"window_name" TEXT NOT NULL PRIMARY KEY,
+		"start_checkpoint" INTEGER NOT NULL,
+		"end_checkpoint" INTEGER NOT NULL,
+		"checkpoint_count" INTEGER NOT NULL
+	)`
+
+	_, err = db.Exec(createWindowStateTableSQL)
+	if err != nil {
+		slog.Error("Error while creating window state table", "error", err)
+		return err
+	}
+
+	// create window counters table - holds the number of checkpoints signed by
+	// each validator within the range of each performance window
+	createWindowCountersTableSQL := `CREATE TABLE IF NOT EXISTS window_counters (
+		"window_name" TEXT NOT NULL,
+		"validator_id" INTEGER NOT NULL,
+		"signed" INTEGER NOT NULL,
+		PRIMARY KEY(window_name, validator_id),
+		FOREIGN KEY(window_name) REFERENCES window_state(window_name),
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createWindowCountersTableSQL)
+	if err != nil {
+		slog.Error("Error while creating window counters table", "error", err)
+		return err
+	}
+
+	// create performance benchmark backtest table - holds the performance
+	// benchmark recomputed from the stored signer data, kept apart from the
+	// live values in the checkpoints table
+	createBacktestTableSQL := `CREATE TABLE IF NOT EXISTS performance_benchmark_backtest (
+		"checkpoint_number" INTEGER NOT NULL,
+		"window_size" INTEGER NOT NULL,
+		"factor" REAL NOT NULL,
+		"performance_benchmark" REAL NOT NULL,
+		"median" REAL NOT NULL,
+		"validators" INTEGER NOT NULL,
+		"validators_below" INTEGER NOT NULL,
+		"complete" INTEGER NOT NULL,
+		"computed_at" INTEGER NOT NULL,
+		PRIMARY KEY(checkpoint_number, window_size, factor)
+	)`
+
+	_, err = db.Exec(createBacktestTableSQL)
+	if err != nil {
+		slog.Error("Error while creating performance benchmark backtest table", "error", err)
+		return err
+	}
+
+	// add the lossless reward column to the checkpoints table - the reward
+	// column holds the reward in wei as an integer, which overflows
+	err = addColumnIfMissing(db, "checkpoints", "reward_wei", "TEXT")
+	if err != nil {
+		return err
+	}
+
+	// add the cost of submitting each checkpoint to the checkpoints table
+	for column, definition := range map[string]string{"gas_used": "INTEGER", "effective_gas_price": "TEXT", "cost_wei": "TEXT"} {
+		err = addColumnIfMissing(db, "checkpoints", column, definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	// add the contents of each checkpoint to the checkpoints table, being the
+	// range of Bor blocks it covers and their root hash
+	for column, definition := range map[string]string{"proposer_address": "TEXT", "bor_start_block": "INTEGER", "bor_end_block": "INTEGER",
+		"root_hash": "TEXT", "account_root_hash": "TEXT", "bor_chain_id": "INTEGER"} {
+		err = addColumnIfMissing(db, "checkpoints", column, definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	// create validator stakes table - holds the last known stake of each
+	// validator, including the stake delegated to it
+	createValidatorStakesTableSQL := `CREATE TABLE IF NOT EXISTS validator_stakes (
+		"validator_id" INTEGER NOT NULL PRIMARY KEY,
+		"stake" TEXT NOT NULL,
+		"block_number" INTEGER NOT NULL,
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createValidatorStakesTableSQL)
+	if err != nil {
+		slog.Error("Error while creating validator stakes table", "error", err)
+		return err
+	}
+
+	// create validator stake history table - holds every change to the stake
+	// and commission rate of each validator, by the block it was seen at
+	createValidatorStakeHistoryTableSQL := `CREATE TABLE IF NOT EXISTS validator_stake_history (
+		"validator_id" INTEGER NOT NULL,
+		"block_number" INTEGER NOT NULL,
+		"stake" TEXT NOT NULL,
+		"delegated_stake" TEXT NOT NULL,
+		"commission_rate" INTEGER NOT NULL,
+		PRIMARY KEY(validator_id, block_number),
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createValidatorStakeHistoryTableSQL)
+	if err != nil {
+		slog.Error("Error while creating validator stake history table", "error", err)
+		return err
+	}
+
+	// create estimated rewards table - holds the estimated reward of each
+	// tracked validator for each checkpoint, in wei
+	createEstimatedRewardsTableSQL := `CREATE TABLE IF NOT EXISTS estimated_rewards (
+		"checkpoint_id" INTEGER NOT NULL,
+		"validator_id" INTEGER NOT NULL,
+		"reward" TEXT NOT NULL,
+		"delegator_reward" TEXT NOT NULL,
+		"missed_reward" TEXT NOT NULL,
+		PRIMARY KEY(checkpoint_id, validator_id),
+		FOREIGN KEY(checkpoint_id) REFERENCES checkpoints(id),
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createEstimatedRewardsTableSQL)
+	if err != nil {
+		slog.Error("Error while creating estimated rewards table", "error", err)
+		return err
+	}
+
+	// add what each POL delegated to the validator earned to the estimated
+	// rewards table
+	err = addColumnIfMissing(db, "estimated_rewards", "delegator_reward_per_pol", "TEXT")
+	if err != nil {
+		return err
+	}
+
+	// create pending rewards table - holds the checkpoints whose rewards could
+	// not be estimated when they were processed, to be estimated later
+	createPendingRewardsTableSQL := `CREATE TABLE IF NOT EXISTS pending_rewards (
+		"checkpoint_number" INTEGER NOT NULL PRIMARY KEY
+	)`
+
+	_, err = db.Exec(createPendingRewardsTableSQL)
+	if err != nil {
+		slog.Error("Error while creating pending rewards table", "error", err)
+		return err
+	}
+
+	// create failed submissions table - holds the submitCheckpoint
+	// transactions which reverted, attributed to a validator by their sender
+	// where possible
+	createFailedSubmissionsTableSQL := `CREATE TABLE IF NOT EXISTS failed_submissions (
+		"tx_hash" TEXT NOT NULL PRIMARY KEY,
+		"block_number" INTEGER NOT NULL,
+		"timestamp" INTEGER NOT NULL,
+		"sender" TEXT NOT NULL,
+		"validator_id" INTEGER,
+		"gas_used" INTEGER NOT NULL,
+		"effective_gas_price" TEXT NOT NULL,
+		"cost_wei" TEXT NOT NULL,
+		"revert_reason" TEXT NOT NULL,
+		FOREIGN KEY(validator_id) REFERENCES validators(id)
+	)`
+
+	_, err = db.Exec(createFailedSubmissionsTableSQL)
+	if err != nil {
+		slog.Error("Error while creating failed submissions table", "error", err)
+		return err
+	}
+
+	// create failed submissions scan table - holds the next block to look for
+	// failed submissions in, as they are scanned separately from the
+	// processing of checkpoints
+	createFailedSubmissionsScanTableSQL := `CREATE TABLE IF NOT EXISTS failed_submissions_scan (
+		"id" INTEGER NOT NULL PRIMARY KEY CHECK (id = 1),
+		"next_block" INTEGER NOT NULL
+	)`
+
+	_, err = db.Exec(createFailedSubmissionsScanTableSQL)
+	if err != nil {
+		slog.Error("Error while creating failed submissions scan table", "error", err)
+		return err
+	}
+
+	// create checkpoint verification table - holds the result of recomputing
+	// the root hash of the Bor blocks covered by each checkpoint
+	createCheckpointVerificationTableSQL := `CREATE TABLE IF NOT EXISTS checkpoint_verification (
+		"checkpoint_number" INTEGER NOT NULL PRIMARY KEY,
+		"bor_start_block" INTEGER NOT NULL,
+		"bor_end_block" INTEGER NOT NULL,
+		"submitted_root_hash" TEXT NOT NULL,
+		"computed_root_hash" TEXT NOT NULL,
+		"match" INTEGER NOT NULL,
+		"verified_at" INTEGER NOT NULL
+	)`
+
+	_, err = db.Exec(createCheckpointVerificationTableSQL)
+	if err != nil {
+		slog.Error("Error while creating checkpoint verification table", "error", err)
+		return err
+	}
+
+	// create checkpoint verification scan table - holds the next checkpoint to
+	// verify, as checkpoints are verified separately from their processing
+	createVerificationScanTableSQL := `CREATE TABLE IF NOT EXISTS checkpoint_verification_scan (
+		"id" INTEGER NOT NULL PRIMARY KEY CHECK (id = 1),
+		"next_checkpoint" INTEGER NOT NULL
+	)`
+
+	_, err = db.Exec(createVerificationScanTableSQL)
+	if err != nil {
+		slog.Error("Error while creating checkpoint verification scan table", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds the passed column to the passed table, unless the
+// table already has it.
+func addColumnIfMissing(db *sql.DB, table string, column string, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		slog.Error("Error while querying for table columns", "table", table, "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+
+		err = rows.Scan(&name)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN "` + column + `" ` + definition)
+	if err != nil {
+		slog.Error("Error while adding column to table", "table", table, "column", column, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// CheckDatabase checks that the database exists and can be queried, without
+// creating it if it does not exist.
+func CheckDatabase() error {
+	_, err := utils.CheckIfDBExists()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	var one int
+	err = db.QueryRow(`SELECT 1 FROM checkpoints LIMIT 1`).Scan(&one)
+	if err != nil && err != sql.ErrNoRows {
 		return err
 	}
 
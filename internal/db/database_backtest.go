@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// GetSignersPerCheckpoint gets the IDs of the validators which signed each
+// checkpoint within the range provided, keyed by checkpoint number. Signers
+// are taken from both the validators_signed_checkpoints table and the
+// temporary table. Checkpoints in the database without any signers are
+// included with an empty list.
+func GetSignersPerCheckpoint(startNumber int, endNumber int) (map[int][]int, error) {
+	defer telemetry.ObserveDBQuery("GetSignersPerCheckpoint", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	// UNION removes the signers found in both tables
+	selectSQL := `SELECT c.number, s.validator_id
+			FROM checkpoints c
+			LEFT JOIN (
+				SELECT checkpoint_id, validator_id FROM validators_signed_checkpoints
+				UNION
+				SELECT checkpoint_id, validator_id FROM temp_validators_signed_checkpoints
+			) s
+			ON s.checkpoint_id = c.id
+			WHERE c.number >= ?
+			AND c.number <= ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for signers of checkpoints in range", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int][]int{}
+	for rows.Next() {
+		var number int
+		var validatorId sql.NullInt64
+
+		err = rows.Scan(&number, &validatorId)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+		if _, found := results[number]; !found {
+			results[number] = []int{}
+		}
+		if validatorId.Valid {
+			results[number] = append(results[number], int(validatorId.Int64))
+		}
+	}
+
+	return results, nil
+}
+
+// GetFirstCheckpointNumber gets the smallest checkpoint number in the
+// checkpoints table, or in the temporary table if temp is true.
+func GetFirstCheckpointNumber(temp bool) (int, error) {
+	defer telemetry.ObserveDBQuery("GetFirstCheckpointNumber", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT MIN(number)
+			FROM checkpoints`
+	if temp {
+		selectSQL = `SELECT MIN(c.number)
+			FROM temp_validators_signed_checkpoints vc
+			LEFT JOIN checkpoints c
+			ON vc.checkpoint_id = c.id`
+	}
+
+	var number sql.NullInt64
+	err = db.QueryRow(selectSQL).Scan(&number)
+	if err != nil {
+		slog.Error("Error while querying for first checkpoint number", "error", err)
+		return 0, err
+	}
+	if !number.Valid {
+		return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "minimum checkpoint number not found, the table might be empty"}}
+	}
+
+	return int(number.Int64), nil
+}
+
+// GetPerformanceBenchmarksInRange gets the performance benchmark saved for each
+// checkpoint within the range provided, keyed by checkpoint number.
+// Checkpoints without a performance benchmark are not included.
+func GetPerformanceBenchmarksInRange(startNumber int, endNumber int) (map[int]float64, error) {
+	defer telemetry.ObserveDBQuery("GetPerformanceBenchmarksInRange", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT number, performance_benchmark
+			FROM checkpoints
+			WHERE number >= ?
+			AND number <= ?
+			AND performance_benchmark IS NOT NULL`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for performance benchmarks in range", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]float64{}
+	for rows.Next() {
+		var number int
+		var performanceBenchmark float64
+
+		err = rows.Scan(&number, &performanceBenchmark)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+		results[number] = performanceBenchmark
+	}
+
+	return results, nil
+}
+
+// SaveBacktestResults saves the passed recomputed performance benchmarks in
+// the performance_benchmark_backtest table, replacing any previous result for
+// the same checkpoint, window size and factor.
+func SaveBacktestResults(results []utils.BacktestResult) error {
+	defer telemetry.ObserveDBQuery("SaveBacktestResults", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
+	}
+
+	statement, err := tx.Prepare(`INSERT OR REPLACE INTO performance_benchmark_backtest(checkpoint_number, window_size, factor, performance_benchmark, median, validators, validators_below, complete, computed_at)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
+
+	computedAt := time.Now().Unix()
+	for _, result := range results {
+		_, err = statement.Exec(result.Checkpoint, result.Window, result.Factor, result.PerformanceBenchmark, result.Median, result.Validators, result.ValidatorsBelow, result.Complete, computedAt)
+		if err != nil {
+			slog.Error("Error while saving backtest result", "checkpoint", result.Checkpoint, "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.Error("Error while committing backtest results", "error", err)
+		return err
+	}
+
+	return nil
+}
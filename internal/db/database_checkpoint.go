@@ -2,8 +2,10 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
+	"log/slog"
+	"time"
 
+	"monitor/internal/telemetry"
 	"monitor/internal/utils"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,9 +14,9 @@ import (
 // getCheckpointId gets the checkpoint ID for the checkpoint with the number
 // passed.
 func getCheckpointId(checkpointNumber uint64) (int, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -25,14 +27,14 @@ func getCheckpointId(checkpointNumber uint64) (int, error) {
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(checkpointNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for validator id using public key, error: %v\n", err)
+		slog.Error("Error while querying for validator id using public key", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -42,14 +44,14 @@ func getCheckpointId(checkpointNumber uint64) (int, error) {
 
 		err = rows.Scan(&id)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 
 		return id, nil
 	}
 
-	fmt.Printf("ERR: Could not find checkpoint number %d in database.\n", checkpointNumber)
+	slog.Error("Could not find checkpoint in database", "checkpoint", checkpointNumber)
 	return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "checkpoint with provided number not found"}}
 }
 
@@ -57,9 +59,9 @@ func getCheckpointId(checkpointNumber uint64) (int, error) {
 // database or not.
 func checkIfCheckpointExists(checkpointNumber uint64) (bool, error) {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return false, err
 	}
 	defer db.Close()
@@ -71,7 +73,7 @@ func checkIfCheckpointExists(checkpointNumber uint64) (bool, error) {
 	// prepare the SQL
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return false, err
 	}
 	defer statement.Close()
@@ -79,7 +81,7 @@ func checkIfCheckpointExists(checkpointNumber uint64) (bool, error) {
 	// query the database
 	rows, err := statement.Query(checkpointNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while checking if checkpoint exists in db, error: %v\n", err)
+		slog.Error("Error while checking if checkpoint exists in db", "error", err)
 		return false, err
 	}
 	defer rows.Close()
@@ -90,7 +92,7 @@ func checkIfCheckpointExists(checkpointNumber uint64) (bool, error) {
 
 		err = rows.Scan(&id, &number)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return false, err
 		}
 
@@ -104,6 +106,8 @@ func checkIfCheckpointExists(checkpointNumber uint64) (bool, error) {
 // InsertCheckpoint inserts a new checkpoint in the database given the passed
 // NewHeaderBlockEvent struct and timestamp.
 func InsertCheckpoint(headerEvent utils.NewHeaderBlockEvent, timestamp uint64) error {
+	defer telemetry.ObserveDBQuery("InsertCheckpoint", time.Now())
+
 	// check if checkpoint already exists in db
 	checkpointExists, err := checkIfCheckpointExists(headerEvent.HeaderBlockId.Uint64())
 	if err != nil {
@@ -123,7 +127,7 @@ func InsertCheckpoint(headerEvent utils.NewHeaderBlockEvent, timestamp uint64) e
 			// in case we cannot find the proposer, set the proposer ID to -1
 			// and insert a blank validator
 			proposerId = -1
-			fmt.Printf("WARN: Could not find validator ID for proposer with signing key %s. The signing key has most likely been changed.\n", headerEvent.ProposerAddress.String())
+			slog.Warn("Could not find validator ID for proposer. The signing key has most likely been changed", "checkpoint", headerEvent.HeaderBlockId.Uint64(), "signer", headerEvent.ProposerAddress.String())
 			err2 := insertBlankValidator()
 			if err2 != nil {
 				return err2
@@ -135,26 +139,33 @@ func InsertCheckpoint(headerEvent utils.NewHeaderBlockEvent, timestamp uint64) e
 
 	if !checkpointExists {
 		// open the database
-		db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+		db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 		if err != nil {
-			fmt.Printf("ERR: Could not open database, error: %v\n", err)
+			slog.Error("Could not open database", "error", err)
 			return err
 		}
 		defer db.Close()
 
-		insertSQL := `INSERT INTO checkpoints(number, block_number, timestamp, proposer_id, reward)
-				VALUES(?, ?, ?, ?, ?)`
+		insertSQL := `INSERT INTO checkpoints(number, block_number, timestamp, proposer_id, reward, reward_wei)
+				VALUES(?, ?, ?, ?, ?, ?)`
 
 		statement, err := db.Prepare(insertSQL)
 		if err != nil {
-			fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+			slog.Error("Error while preparing SQL statement", "error", err)
 			return err
 		}
 		defer statement.Close()
 
-		_, err = statement.Exec(headerEvent.HeaderBlockId.Int64(), headerEvent.BlockNumber, timestamp, proposerId, headerEvent.Reward.Int64())
+		// the reward is also kept as an integer for compatibility, but only
+		// when it fits, as rewards in wei usually do not
+		var reward sql.NullInt64
+		if headerEvent.Reward.IsInt64() {
+			reward = sql.NullInt64{Int64: headerEvent.Reward.Int64(), Valid: true}
+		}
+
+		_, err = statement.Exec(headerEvent.HeaderBlockId.Int64(), headerEvent.BlockNumber, timestamp, proposerId, reward, headerEvent.Reward.String())
 		if err != nil {
-			fmt.Printf("ERR: Error while executing checkpoint insert, error: %v\n", err)
+			slog.Error("Error while executing checkpoint insert", "checkpoint", headerEvent.HeaderBlockId.Uint64(), "block", headerEvent.BlockNumber, "error", err)
 			return err
 		}
 
@@ -168,15 +179,24 @@ func InsertCheckpoint(headerEvent utils.NewHeaderBlockEvent, timestamp uint64) e
 // table with the respective signers for the given checkpoint number. If temp is
 // true, they are inserted in the temporary table instead.
 func InsertValidatorsSignedCheckpoint(checkpointNumber uint64, signers []string, temp bool) error {
-	// if we are not tracking any validators and we are not inserting in temp,
-	// then there is nothing to do
-	if len(utils.Config.PublicKeys) == 0 && !temp {
-		fmt.Println("WARN: No public keys provided in config to track. The tool will not be tracking the performance of any validator.")
-		return nil
-	}
+	defer telemetry.ObserveDBQuery("InsertValidatorsSignedCheckpoint", time.Now())
 
-	// see if we are tracking all validators
-	trackAll := utils.CheckIfTrackAll()
+	// get the IDs of the validators we are tracking
+	trackedIds := []int{}
+	if !temp {
+		var err error
+		trackedIds, err = ResolveTrackedValidators()
+		if err != nil {
+			return err
+		}
+
+		// if we are not tracking any validators and we are not inserting in
+		// temp, then there is nothing to do
+		if len(trackedIds) == 0 {
+			slog.Warn("No validators to track found in config. The tool will not be tracking the performance of any validator")
+			return nil
+		}
+	}
 
 	// get checkpoint id from database
 	checkpointId, err := getCheckpointId(checkpointNumber)
@@ -184,9 +204,9 @@ func InsertValidatorsSignedCheckpoint(checkpointNumber uint64, signers []string,
 		return err
 	}
 
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -204,52 +224,53 @@ func InsertValidatorsSignedCheckpoint(checkpointNumber uint64, signers []string,
 
 	statement, err := db.Prepare(insertSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
 
 	for _, validator := range signers {
-		if temp || trackAll || utils.ContainsString(utils.Config.PublicKeys, validator) {
-			// get validator id from database
-			validatorId, err := getValidatorIdDB(validator)
-			validatorFound := true
-			if err != nil {
-				switch err.(type) {
-				case *utils.ValidatorNotFoundError:
-					fmt.Printf("WARN: Could not find validator with signer key %s in database. This validator most likely changed the signing key.\n", validator)
-					validatorFound = false
-				default:
-					return err
-				}
-			}
-
-			if validatorFound {
-				alreadyInserted := false
+		// get validator id from database
+		validatorId, err := getValidatorIdDB(validator)
+		validatorFound := true
+		if err != nil {
+			switch err.(type) {
+			case *utils.ValidatorNotFoundError:
+				// only warn once, as all signers are inserted in temp
 				if temp {
-					// check if we have already inserted this checkpointId validatorId combo
-					alreadyInserted, err = checkIfValidatorInTemp(checkpointId, validatorId)
-					if err != nil {
-						return err
-					}
-				} else {
-					alreadyInserted, err = checkIfValidatorInSigned(checkpointId, validatorId)
-					if err != nil {
-						return err
-					}
+					slog.Warn("Could not find validator with signer key in database. This validator most likely changed the signing key", "checkpoint", checkpointNumber, "signer", validator)
 				}
+				validatorFound = false
+			default:
+				return err
+			}
+		}
 
-				// if we have already inserted, do not insert again
-				if alreadyInserted {
-					continue
+		if validatorFound && (temp || utils.Contains(trackedIds, validatorId)) {
+			alreadyInserted := false
+			if temp {
+				// check if we have already inserted this checkpointId validatorId combo
+				alreadyInserted, err = checkIfValidatorInTemp(checkpointId, validatorId)
+				if err != nil {
+					return err
 				}
-
-				_, err = statement.Exec(checkpointId, validatorId)
+			} else {
+				alreadyInserted, err = checkIfValidatorInSigned(checkpointId, validatorId)
 				if err != nil {
-					fmt.Printf("ERR: Error while executing checkpoint and validator insert, error: %v\n", err)
 					return err
 				}
 			}
+
+			// if we have already inserted, do not insert again
+			if alreadyInserted {
+				continue
+			}
+
+			_, err = statement.Exec(checkpointId, validatorId)
+			if err != nil {
+				slog.Error("Error while executing checkpoint and validator insert", "checkpoint", checkpointNumber, "validator_id", validatorId, "error", err)
+				return err
+			}
 		}
 	}
 
@@ -258,17 +279,13 @@ func InsertValidatorsSignedCheckpoint(checkpointNumber uint64, signers []string,
 
 // GetFirstMissedCheckpointRange gets the first checkpoint a particular
 // validator missed within the range provided.
-func GetFirstMissedCheckpointRange(signerKey string, startNumber int, endNumber int) (int, error) {
-	// first get the validator's id
-	validatorId, err := getValidatorIdDB(signerKey)
-	if err != nil {
-		return 0, err
-	}
+func GetFirstMissedCheckpointRange(validatorId int, startNumber int, endNumber int) (int, error) {
+	defer telemetry.ObserveDBQuery("GetFirstMissedCheckpointRange", time.Now())
 
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -283,14 +300,14 @@ func GetFirstMissedCheckpointRange(signerKey string, startNumber int, endNumber
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(validatorId, startNumber, endNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for the first missed checkpoint in range, error: %v\n", err)
+		slog.Error("Error while querying for the first missed checkpoint in range", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -300,20 +317,21 @@ func GetFirstMissedCheckpointRange(signerKey string, startNumber int, endNumber
 
 		err = rows.Scan(&checkpointNumber)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 
 		return checkpointNumber, nil
 	}
 
-	fmt.Printf("ERR: Could not find first missed checkpoint for validator %v in database.\n", signerKey)
+	slog.Error("Could not find first missed checkpoint for validator in database", "validator_id", validatorId)
 	return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "first missed checkpoint not found"}}
 }
 
 // CheckIfCheckpointExistsInTemp checks in the passed checkpointNumber exists in
 // the temporary table.
 func CheckIfCheckpointExistsInTemp(checkpointNumber uint64) (bool, error) {
+	defer telemetry.ObserveDBQuery("CheckIfCheckpointExistsInTemp", time.Now())
 
 	// get checkpoint id from database
 	checkpointId, err := getCheckpointId(checkpointNumber)
@@ -321,9 +339,9 @@ func CheckIfCheckpointExistsInTemp(checkpointNumber uint64) (bool, error) {
 		return false, err
 	}
 
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return false, err
 	}
 	defer db.Close()
@@ -334,14 +352,14 @@ func CheckIfCheckpointExistsInTemp(checkpointNumber uint64) (bool, error) {
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return false, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(checkpointId)
 	if err != nil {
-		fmt.Printf("ERR: Error while checking if checkpoint exists in temporary db, error: %v\n", err)
+		slog.Error("Error while checking if checkpoint exists in temporary db", "error", err)
 		return false, err
 	}
 	defer rows.Close()
@@ -352,7 +370,7 @@ func CheckIfCheckpointExistsInTemp(checkpointNumber uint64) (bool, error) {
 
 		err = rows.Scan(&checkpoint_id, &validator_id)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return false, err
 		}
 		if checkpoint_id.Valid {
@@ -369,15 +387,17 @@ func CheckIfCheckpointExistsInTemp(checkpointNumber uint64) (bool, error) {
 // signed checkpoints table to get a count of how many checkpoints each
 // validator signed.
 func GetSignedCheckpointsCountPerValidator(startNumber int, endNumber int) (int, map[int]int, error) {
+	defer telemetry.ObserveDBQuery("GetSignedCheckpointsCountPerValidator", time.Now())
+
 	// get the number of checkpoints in range
 	numOfCheckpoints, err := getNumberOfCheckpointsBetweenRange(startNumber, endNumber)
 	if err != nil {
 		return 0, nil, err
 	}
 
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, nil, err
 	}
 	defer db.Close()
@@ -394,14 +414,14 @@ func GetSignedCheckpointsCountPerValidator(startNumber int, endNumber int) (int,
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, nil, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(startNumber, endNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for validators' signed checkpoints in range, error: %v\n", err)
+		slog.Error("Error while querying for validators' signed checkpoints in range", "error", err)
 		return 0, nil, err
 	}
 	defer rows.Close()
@@ -413,7 +433,7 @@ func GetSignedCheckpointsCountPerValidator(startNumber int, endNumber int) (int,
 
 		err = rows.Scan(&validatorId, &count)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, nil, err
 		}
 		results[validatorId] = count
@@ -422,13 +442,101 @@ func GetSignedCheckpointsCountPerValidator(startNumber int, endNumber int) (int,
 	return numOfCheckpoints, results, nil
 }
 
+// GetSetPerformance700 gets the performance of every validator in the set
+// over the 700 checkpoints ending at the passed checkpoint, from the temporary
+// table. Validators which did not sign any of these checkpoints are not
+// included, as is the case when calculating the performance benchmark.
+func GetSetPerformance700(checkpointNumber int) ([]utils.ValidatorPerformance, error) {
+	defer telemetry.ObserveDBQuery("GetSetPerformance700", time.Now())
+
+	checkpointCount, validatorsPerformance, err := GetSignedCheckpointsCountPerValidator(checkpointNumber-(utils.PB_WINDOW-1), checkpointNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []utils.ValidatorPerformance{}
+	if checkpointCount == 0 {
+		return results, nil
+	}
+
+	for validatorId, signed := range validatorsPerformance {
+		validator, err := GetValidator(validatorId)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, utils.ValidatorPerformance{
+			Validator:   validator,
+			Signed:      signed,
+			Checkpoints: checkpointCount,
+			Performance: float64(signed) / float64(checkpointCount),
+			Active:      validator.InPerformanceBenchmark(uint64(checkpointNumber)),
+		})
+	}
+
+	return results, nil
+}
+
+// GetSignedCheckpointNumbersPerValidator gets the numbers of the checkpoints
+// signed by each validator within the range provided, from the temporary
+// table. The results are keyed by validator id, and each list is sorted.
+func GetSignedCheckpointNumbersPerValidator(startNumber int, endNumber int) (map[int][]int, error) {
+	defer telemetry.ObserveDBQuery("GetSignedCheckpointNumbersPerValidator", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT vc.validator_id, c.number
+			FROM temp_validators_signed_checkpoints vc
+			LEFT JOIN checkpoints c
+			ON vc.checkpoint_id = c.id
+			WHERE c.number >= ?
+			AND c.number <= ?
+			ORDER BY c.number`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for validators' signed checkpoints in range", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int][]int{}
+	for rows.Next() {
+		var validatorId int
+		var number int
+
+		err = rows.Scan(&validatorId, &number)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+		results[validatorId] = append(results[validatorId], number)
+	}
+
+	return results, nil
+}
+
 // DeleteTempCheckpoints deletes all checkpoints from the temporary table which
 // have a checkpoint number smaller than the one passed. For tracked validators,
 // that data is still available in the validators_signed_checkpoints table.
 func DeleteTempCheckpoints(endNumber uint64) error {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("DeleteTempCheckpoints", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -442,14 +550,14 @@ func DeleteTempCheckpoints(endNumber uint64) error {
 
 	statement, err := db.Prepare(deleteSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
 
 	_, err = statement.Exec(endNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while deleting checkpoints from the temporary signed checkpoints table, error: %v\n", err)
+		slog.Error("Error while deleting checkpoints from the temporary signed checkpoints table", "error", err)
 		return err
 	}
 
@@ -459,9 +567,11 @@ func DeleteTempCheckpoints(endNumber uint64) error {
 // InsertPerformanceBenchmark inserts the performance benchmark for the given
 // checkpoint number in the checkpoints table.
 func InsertPerformanceBenchmark(pb float64, checkpointNumber int) error {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("InsertPerformanceBenchmark", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -472,14 +582,14 @@ func InsertPerformanceBenchmark(pb float64, checkpointNumber int) error {
 
 	statement, err := db.Prepare(insertSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
 
 	_, err = statement.Exec(pb, checkpointNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while executing performance benchmark insert, error: %v\n", err)
+		slog.Error("Error while executing performance benchmark insert", "checkpoint", checkpointNumber, "error", err)
 		return err
 	}
 
@@ -489,9 +599,11 @@ func InsertPerformanceBenchmark(pb float64, checkpointNumber int) error {
 // GetLastCheckpointNumber gets the last / largest checkpoint number in the
 // checkpoints table.
 func GetLastCheckpointNumber() (int, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("GetLastCheckpointNumber", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -501,14 +613,14 @@ func GetLastCheckpointNumber() (int, error) {
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query()
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for maximum checkpoint number, error: %v\n", err)
+		slog.Error("Error while querying for maximum checkpoint number", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -518,7 +630,7 @@ func GetLastCheckpointNumber() (int, error) {
 
 		err = rows.Scan(&checkpointNumber)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 		if checkpointNumber.Valid {
@@ -534,9 +646,9 @@ func GetLastCheckpointNumber() (int, error) {
 // getNumberOfCheckpointsBetweenRange returns the number of rows between two
 // numbers, both inclusive.
 func getNumberOfCheckpointsBetweenRange(startNumber int, endNumber int) (int, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -547,14 +659,14 @@ func getNumberOfCheckpointsBetweenRange(startNumber int, endNumber int) (int, er
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(startNumber, endNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for number of checkpoints between range, error: %v\n", err)
+		slog.Error("Error while querying for number of checkpoints between range", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -564,7 +676,7 @@ func getNumberOfCheckpointsBetweenRange(startNumber int, endNumber int) (int, er
 
 		err = rows.Scan(&number)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 
@@ -574,61 +686,14 @@ func getNumberOfCheckpointsBetweenRange(startNumber int, endNumber int) (int, er
 	return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "error while trying to get count of checkpoints between two numbers"}}
 }
 
-// getSignedCheckpointsCount gets the number of signed checkpoints for the given
-// signer key, for the given range. The validator must be tracked, as this
-// does not query the temporary table.
-func getSignedCheckpointsCount(startNumber int, endNumber int, signerKey string) (int, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
-	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
-		return 0, err
-	}
-	defer db.Close()
-
-	selectSQL := `SELECT COUNT(*)
-			FROM validators_signed_checkpoints vc
-			LEFT JOIN checkpoints c
-			ON vc.checkpoint_id = c.id
-			LEFT JOIN validators v
-			ON vc.validator_id = v.id
-			WHERE c.number >= ?
-			AND c.number <= ?
-			AND v.signer_key LIKE ?`
-
-	statement, err := db.Prepare(selectSQL)
-	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
-		return 0, err
-	}
-	defer statement.Close()
-
-	rows, err := statement.Query(startNumber, endNumber, signerKey)
-	if err != nil {
-		fmt.Printf("ERR: Error while querying for number of signed checkpoints in range, error: %v\n", err)
-		return 0, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var count int
-
-		err = rows.Scan(&count)
-		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
-			return 0, err
-		}
-		return count, nil
-	}
-
-	return 0, &utils.GenericError{Message: "error while trying to get signed checkpoints by public key"}
-}
-
 // GetLastBlockNumber gets the last block number from the last checkpoint in the
 // database.
 func GetLastBlockNumber() (uint64, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("GetLastBlockNumber", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -638,14 +703,14 @@ func GetLastBlockNumber() (uint64, error) {
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query()
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for maximum block number, error: %v\n", err)
+		slog.Error("Error while querying for maximum block number", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -655,7 +720,7 @@ func GetLastBlockNumber() (uint64, error) {
 
 		err = rows.Scan(&blockNumber)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 		if blockNumber.Valid {
@@ -671,9 +736,11 @@ func GetLastBlockNumber() (uint64, error) {
 // GetPBAtCheckpoint gets the performance benchmark at the provided checkpoint
 // number.
 func GetPBAtCheckpoint(checkpointNumber int) (float64, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("GetPBAtCheckpoint", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -684,14 +751,14 @@ func GetPBAtCheckpoint(checkpointNumber int) (float64, error) {
 
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
 
 	rows, err := statement.Query(checkpointNumber)
 	if err != nil {
-		fmt.Printf("ERR: Error while getting maximum validator id from database, error: %v\n", err)
+		slog.Error("Error while getting maximum validator id from database", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -701,7 +768,7 @@ func GetPBAtCheckpoint(checkpointNumber int) (float64, error) {
 
 		err = rows.Scan(&performanceBenchmark)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 		if performanceBenchmark.Valid {
@@ -715,84 +782,57 @@ func GetPBAtCheckpoint(checkpointNumber int) (float64, error) {
 
 }
 
-// getCheckpointPerformanceRangeOnly gets the number of signed checkpoints by
-// a validator for the given range.
-func getCheckpointPerformanceRangeOnly(startNumber int, endNumber int, publicKey string) (int, error) {
+// DeleteCheckpoint removes the checkpoint with the passed number, along with
+// everything stored for it, from the database. It is used to roll back a
+// checkpoint which could not be fully processed. The cost and contents of the
+// checkpoint are columns of its row, so they are removed along with it.
+func DeleteCheckpoint(checkpointNumber uint64) error {
+	defer telemetry.ObserveDBQuery("DeleteCheckpoint", time.Now())
 
-	// get the number of checkpoints the passed public key signed for the same period
-	numSignedCheckpoints, err := getSignedCheckpointsCount(startNumber, endNumber, publicKey)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		return 0, err
+		slog.Error("Could not open database", "error", err)
+		return err
 	}
+	defer db.Close()
 
-	return numSignedCheckpoints, nil
-}
-
-// getCheckpointPerformanceRangeAll gets the number of signed checkpoints by
-// all the validators that are tracked, for the given range.
-func getCheckpointPerformanceRangeAll(startNumber int, endNumber int) (int, map[string]int, error) {
-
-	// get the number of checkpoints in range
-	numOfCheckpoints, err := getNumberOfCheckpointsBetweenRange(startNumber, endNumber)
+	// delete everything in one transaction, so that we never leave the
+	// checkpoint partially deleted
+	tx, err := db.Begin()
 	if err != nil {
-		return 0, nil, err
-	}
-
-	if len(utils.Config.PublicKeys) == 0 {
-		// no keys to check performance for, just return number of checkpoints in range
-		return numOfCheckpoints, nil, nil
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
 	}
 
-	trackAll := false
-	if len(utils.Config.PublicKeys) == 1 {
-		// if we have a '*', then we are monitoring the performance of all validators
-		if utils.Config.PublicKeys[0] == "*" {
-			trackAll = true
-		}
+	deleteSQLs := []string{
+		`DELETE FROM validators_signed_checkpoints
+			WHERE checkpoint_id IN (SELECT id FROM checkpoints WHERE number = ?)`,
+		`DELETE FROM temp_validators_signed_checkpoints
+			WHERE checkpoint_id IN (SELECT id FROM checkpoints WHERE number = ?)`,
+		`DELETE FROM estimated_rewards
+			WHERE checkpoint_id IN (SELECT id FROM checkpoints WHERE number = ?)`,
+		`DELETE FROM checkpoint_verification
+			WHERE checkpoint_number = ?`,
+		`DELETE FROM pending_rewards
+			WHERE checkpoint_number = ?`,
+		`DELETE FROM checkpoints
+			WHERE number = ?`,
 	}
 
-	if trackAll {
-		// in this case, get all validators' performance
-		publicKeys, err := getAllValidatorsSignerKeys()
+	for _, deleteSQL := range deleteSQLs {
+		_, err = tx.Exec(deleteSQL, checkpointNumber)
 		if err != nil {
-			return 0, nil, err
-		}
-		if len(publicKeys) == 0 {
-			// no pubkeys to check, return num of checpoints only
-			return numOfCheckpoints, nil, nil
-		} else {
-			results := map[string]int{}
-
-			for _, publicKey := range publicKeys {
-				signedCheckpoints, err := getCheckpointPerformanceRangeOnly(startNumber, endNumber, publicKey)
-				if err != nil {
-					return numOfCheckpoints, nil, err
-				}
-				results[publicKey] = signedCheckpoints
-			}
-
-			return numOfCheckpoints, results, nil
-		}
-	} else {
-		results := map[string]int{}
-		for _, publicKey := range utils.Config.PublicKeys {
-			signedCheckpoints, err := getCheckpointPerformanceRangeOnly(startNumber, endNumber, publicKey)
-			if err != nil {
-				return numOfCheckpoints, nil, err
-			}
-			results[publicKey] = signedCheckpoints
+			slog.Error("Error while deleting checkpoint", "checkpoint", checkpointNumber, "error", err)
+			tx.Rollback()
+			return err
 		}
-		return numOfCheckpoints, results, nil
 	}
-}
 
-// GetCheckpointCount is a helper function that calls another function to get
-// the total checkpoints and how many of those were signed by the validators
-// we are tracking, for the range provided.
-func GetCheckpointCount(startNumber int, endNumber int) (int, map[string]int, error) {
-	totalCheckpoints, signedCheckpointsMap, err := getCheckpointPerformanceRangeAll(startNumber, endNumber)
+	err = tx.Commit()
 	if err != nil {
-		return 0, nil, err
+		slog.Error("Error while committing checkpoint deletion", "error", err)
+		return err
 	}
-	return totalCheckpoints, signedCheckpointsMap, nil
+
+	return nil
 }
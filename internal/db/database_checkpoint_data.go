@@ -0,0 +1,80 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// InsertCheckpointData saves the contents of the passed checkpoint, being the
+// proposer, the range of Bor blocks it covers, their root hash, the account
+// root hash and the Bor chain id.
+func InsertCheckpointData(checkpointNumber uint64, data utils.CheckpointData) error {
+	defer telemetry.ObserveDBQuery("InsertCheckpointData", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	updateSQL := `UPDATE checkpoints
+			SET proposer_address = ?, bor_start_block = ?, bor_end_block = ?, root_hash = ?, account_root_hash = ?, bor_chain_id = ?
+			WHERE number = ?`
+
+	statement, err := db.Prepare(updateSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(data.Proposer.String(), data.StartBlock, data.EndBlock, data.RootHash.String(), data.AccountRootHash.String(), data.BorChainId, checkpointNumber)
+	if err != nil {
+		slog.Error("Error while updating checkpoint data", "checkpoint", checkpointNumber, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetCheckpointData gets the contents of the passed checkpoint. It returns a
+// CheckpointNotFoundError if the checkpoint or its contents are not stored.
+func GetCheckpointData(checkpointNumber int) (utils.CheckpointData, error) {
+	defer telemetry.ObserveDBQuery("GetCheckpointData", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return utils.CheckpointData{}, err
+	}
+	defer db.Close()
+
+	var proposer, rootHash, accountRootHash sql.NullString
+	var startBlock, endBlock, borChainId sql.NullInt64
+	err = db.QueryRow(`SELECT proposer_address, bor_start_block, bor_end_block, root_hash, account_root_hash, bor_chain_id
+			FROM checkpoints
+			WHERE number = ?`, checkpointNumber).Scan(&proposer, &startBlock, &endBlock, &rootHash, &accountRootHash, &borChainId)
+	if err == sql.ErrNoRows || (err == nil && !rootHash.Valid) {
+		return utils.CheckpointData{}, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "checkpoint data not found"}}
+	} else if err != nil {
+		slog.Error("Error while querying for checkpoint data", "checkpoint", checkpointNumber, "error", err)
+		return utils.CheckpointData{}, err
+	}
+
+	return utils.CheckpointData{
+		Proposer:        common.HexToAddress(proposer.String),
+		StartBlock:      uint64(startBlock.Int64),
+		EndBlock:        uint64(endBlock.Int64),
+		RootHash:        common.HexToHash(rootHash.String),
+		AccountRootHash: common.HexToHash(accountRootHash.String),
+		BorChainId:      uint64(borChainId.Int64),
+	}, nil
+}
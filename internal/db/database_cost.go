@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// InsertCheckpointCost saves the gas used, the effective gas price and the
+// cost of the transaction which submitted the passed checkpoint.
+func InsertCheckpointCost(checkpointNumber uint64, cost utils.TransactionCost) error {
+	defer telemetry.ObserveDBQuery("InsertCheckpointCost", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	updateSQL := `UPDATE checkpoints
+			SET gas_used = ?, effective_gas_price = ?, cost_wei = ?
+			WHERE number = ?`
+
+	statement, err := db.Prepare(updateSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(cost.GasUsed, cost.EffectiveGasPrice.String(), cost.Cost.String(), checkpointNumber)
+	if err != nil {
+		slog.Error("Error while updating checkpoint cost", "checkpoint", checkpointNumber, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetCheckpointCosts gets the cost of submitting each checkpoint within the
+// range provided, keyed by checkpoint number. Checkpoints whose cost is not
+// known are not included.
+func GetCheckpointCosts(startNumber int, endNumber int) (map[int]utils.TransactionCost, error) {
+	defer telemetry.ObserveDBQuery("GetCheckpointCosts", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT number, gas_used, effective_gas_price, cost_wei
+			FROM checkpoints
+			WHERE number >= ?
+			AND number <= ?
+			AND cost_wei IS NOT NULL`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for checkpoint costs in range", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]utils.TransactionCost{}
+	for rows.Next() {
+		var number int
+		var gasUsed uint64
+		var gasPriceText, costText string
+
+		err = rows.Scan(&number, &gasUsed, &gasPriceText, &costText)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		gasPrice, ok := new(big.Int).SetString(gasPriceText, 10)
+		if !ok {
+			slog.Warn("Could not parse gas price of checkpoint", "checkpoint", number, "gas_price", gasPriceText)
+			continue
+		}
+		cost, ok := new(big.Int).SetString(costText, 10)
+		if !ok {
+			slog.Warn("Could not parse cost of checkpoint", "checkpoint", number, "cost", costText)
+			continue
+		}
+
+		results[number] = utils.TransactionCost{GasUsed: gasUsed, EffectiveGasPrice: gasPrice, Cost: cost}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// GetSignedCountsInRange gets the number of checkpoints within the range
+// provided, and the number of those signed by each validator in the
+// validators_signed_checkpoints table, keyed by validator id.
+func GetSignedCountsInRange(startNumber int, endNumber int) (int, map[int]int, error) {
+	defer telemetry.ObserveDBQuery("GetSignedCountsInRange", time.Now())
+
+	// get the number of checkpoints in range
+	numOfCheckpoints, err := getNumberOfCheckpointsBetweenRange(startNumber, endNumber)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT vc.validator_id, COUNT(*)
+			FROM validators_signed_checkpoints vc
+			LEFT JOIN checkpoints c
+			ON vc.checkpoint_id = c.id
+			WHERE c.number >= ?
+			AND c.number <= ?
+			GROUP BY vc.validator_id`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return 0, nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for number of signed checkpoints in range", "error", err)
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]int{}
+	for rows.Next() {
+		var validatorId int
+		var count int
+
+		err = rows.Scan(&validatorId, &count)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return 0, nil, err
+		}
+		results[validatorId] = count
+	}
+
+	return numOfCheckpoints, results, nil
+}
+
+// GetWindowCounters gets the saved running counters of the passed performance
+// window. The second return value is false if no counters were saved for it.
+func GetWindowCounters(window string) (utils.WindowCounters, bool, error) {
+	defer telemetry.ObserveDBQuery("GetWindowCounters", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return utils.WindowCounters{}, false, err
+	}
+	defer db.Close()
+
+	counters := utils.WindowCounters{Window: window, Signed: map[int]int{}}
+
+	// get the range covered by the counters
+	err = db.QueryRow(`SELECT start_checkpoint, end_checkpoint, checkpoint_count
+			FROM window_state
+			WHERE window_name = ?`, window).Scan(&counters.Start, &counters.End, &counters.Checkpoints)
+	if err == sql.ErrNoRows {
+		return utils.WindowCounters{}, false, nil
+	} else if err != nil {
+		slog.Error("Error while querying for window state", "window", window, "error", err)
+		return utils.WindowCounters{}, false, err
+	}
+
+	// get the counters of each validator
+	rows, err := db.Query(`SELECT validator_id, signed
+			FROM window_counters
+			WHERE window_name = ?`, window)
+	if err != nil {
+		slog.Error("Error while querying for window counters", "window", window, "error", err)
+		return utils.WindowCounters{}, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var validatorId int
+		var signed int
+
+		err = rows.Scan(&validatorId, &signed)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return utils.WindowCounters{}, false, err
+		}
+		counters.Signed[validatorId] = signed
+	}
+
+	return counters, true, nil
+}
+
+// SaveWindowCounters saves the running counters of a performance window,
+// replacing the ones previously saved.
+func SaveWindowCounters(counters utils.WindowCounters) error {
+	defer telemetry.ObserveDBQuery("SaveWindowCounters", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	// save everything in one transaction, so that the state and the counters
+	// always match
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO window_state(window_name, start_checkpoint, end_checkpoint, checkpoint_count)
+			VALUES(?, ?, ?, ?)`, counters.Window, counters.Start, counters.End, counters.Checkpoints)
+	if err != nil {
+		slog.Error("Error while saving window state", "window", counters.Window, "error", err)
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM window_counters
+			WHERE window_name = ?`, counters.Window)
+	if err != nil {
+		slog.Error("Error while deleting window counters", "window", counters.Window, "error", err)
+		tx.Rollback()
+		return err
+	}
+
+	statement, err := tx.Prepare(`INSERT INTO window_counters(window_name, validator_id, signed)
+			VALUES(?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
+
+	for validatorId, signed := range counters.Signed {
+		if signed == 0 {
+			continue
+		}
+
+		_, err = statement.Exec(counters.Window, validatorId, signed)
+		if err != nil {
+			slog.Error("Error while saving window counters", "window", counters.Window, "validator_id", validatorId, "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.Error("Error while committing window counters", "error", err)
+		return err
+	}
+
+	return nil
+}
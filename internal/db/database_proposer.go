@@ -0,0 +1,218 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// saveValidatorStakes saves the stake of each of the passed validators, as
+// fetched at the passed block number, replacing the one previously saved. If
+// the stake or commission rate changed, it is also added to the stake
+// history. Validators without a stake are skipped.
+func saveValidatorStakes(validators []utils.Validator, blockNumber uint64) error {
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
+	}
+
+	statement, err := tx.Prepare(`INSERT OR REPLACE INTO validator_stakes(validator_id, stake, block_number)
+			VALUES(?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
+
+	historyStatement, err := tx.Prepare(`INSERT OR REPLACE INTO validator_stake_history(validator_id, block_number, stake, delegated_stake, commission_rate)
+			VALUES(?, ?, ?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer historyStatement.Close()
+
+	for _, validator := range validators {
+		if validator.Stake == nil {
+			continue
+		}
+
+		_, err = statement.Exec(validator.ValidatorId, validator.Stake.String(), blockNumber)
+		if err != nil {
+			slog.Error("Error while saving validator stake", "validator_id", validator.ValidatorId, "error", err)
+			tx.Rollback()
+			return err
+		}
+
+		delegatedStake := "0"
+		if validator.DelegatedStake != nil {
+			delegatedStake = validator.DelegatedStake.String()
+		}
+
+		// only add to the history if something changed since the last entry
+		var lastStake, lastDelegatedStake string
+		var lastCommissionRate uint64
+		err = tx.QueryRow(`SELECT stake, delegated_stake, commission_rate
+				FROM validator_stake_history
+				WHERE validator_id = ?
+				AND block_number <= ?
+				ORDER BY block_number DESC
+				LIMIT 1`, validator.ValidatorId, blockNumber).Scan(&lastStake, &lastDelegatedStake, &lastCommissionRate)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error("Error while querying for validator stake history", "validator_id", validator.ValidatorId, "error", err)
+			tx.Rollback()
+			return err
+		}
+		if err == nil && lastStake == validator.Stake.String() && lastDelegatedStake == delegatedStake && lastCommissionRate == validator.CommissionRate {
+			continue
+		}
+
+		_, err = historyStatement.Exec(validator.ValidatorId, blockNumber, validator.Stake.String(), delegatedStake, validator.CommissionRate)
+		if err != nil {
+			slog.Error("Error while saving validator stake history", "validator_id", validator.ValidatorId, "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.Error("Error while committing validator stakes", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetActiveValidatorStakes gets the last known stake of every validator which
+// has not been deactivated, in wei, keyed by validator id.
+func GetActiveValidatorStakes() (map[int]*big.Int, error) {
+	defer telemetry.ObserveDBQuery("GetActiveValidatorStakes", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT s.validator_id, s.stake
+			FROM validator_stakes s
+			LEFT JOIN validators v
+			ON s.validator_id = v.id
+			WHERE v.deactivation_epoch IS NULL
+			OR v.deactivation_epoch = 0`
+
+	rows, err := db.Query(selectSQL)
+	if err != nil {
+		slog.Error("Error while querying for validator stakes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]*big.Int{}
+	for rows.Next() {
+		var validatorId int
+		var stakeText string
+
+		err = rows.Scan(&validatorId, &stakeText)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		stake, ok := new(big.Int).SetString(stakeText, 10)
+		if !ok {
+			slog.Warn("Could not parse stake of validator", "validator_id", validatorId, "stake", stakeText)
+			continue
+		}
+		results[validatorId] = stake
+	}
+
+	return results, nil
+}
+
+// GetProposerStatsInRange gets the number of checkpoints within the range
+// provided, and the number of those proposed by each validator along with the
+// sum of their rewards, keyed by validator id. Checkpoints whose proposer is
+// unknown are counted in the total only.
+func GetProposerStatsInRange(startNumber int, endNumber int) (int, map[int]utils.ProposerStats, error) {
+	defer telemetry.ObserveDBQuery("GetProposerStatsInRange", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	// the rewards are summed here rather than in SQL, as they are stored as
+	// text to not lose precision
+	selectSQL := `SELECT proposer_id, reward_wei
+			FROM checkpoints
+			WHERE number >= ?
+			AND number <= ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return 0, nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for checkpoint proposers in range", "error", err)
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	numOfCheckpoints := 0
+	results := map[int]utils.ProposerStats{}
+	for rows.Next() {
+		var proposerId int
+		var rewardText sql.NullString
+
+		err = rows.Scan(&proposerId, &rewardText)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return 0, nil, err
+		}
+
+		numOfCheckpoints++
+		if proposerId <= 0 {
+			continue
+		}
+
+		stats, found := results[proposerId]
+		if !found {
+			stats = utils.ProposerStats{Reward: new(big.Int)}
+		}
+		stats.Proposed++
+		reward, ok := new(big.Int).SetString(rewardText.String, 10)
+		if rewardText.Valid && ok {
+			stats.Reward.Add(stats.Reward, reward)
+		} else {
+			stats.RewardUnknown++
+		}
+		results[proposerId] = stats
+	}
+
+	return numOfCheckpoints, results, nil
+}
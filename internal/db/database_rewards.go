@@ -0,0 +1,316 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// GetValidatorStakesAt gets the stake of every validator as of the passed
+// block number, from the stake history, keyed by validator id. Validators
+// whose stake was first seen after the block are not included.
+func GetValidatorStakesAt(blockNumber uint64) (map[int]utils.ValidatorStake, error) {
+	defer telemetry.ObserveDBQuery("GetValidatorStakesAt", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT h.validator_id, h.stake, h.delegated_stake, h.commission_rate
+			FROM validator_stake_history h
+			WHERE h.block_number = (
+				SELECT MAX(block_number)
+				FROM validator_stake_history
+				WHERE validator_id = h.validator_id
+				AND block_number <= ?
+			)`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(blockNumber)
+	if err != nil {
+		slog.Error("Error while querying for validator stakes at block", "block", blockNumber, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]utils.ValidatorStake{}
+	for rows.Next() {
+		var validatorId int
+		var stakeText, delegatedStakeText string
+		var commissionRate uint64
+
+		err = rows.Scan(&validatorId, &stakeText, &delegatedStakeText, &commissionRate)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		stake, ok := new(big.Int).SetString(stakeText, 10)
+		if !ok {
+			slog.Warn("Could not parse stake of validator", "validator_id", validatorId, "stake", stakeText)
+			continue
+		}
+		delegatedStake, ok := new(big.Int).SetString(delegatedStakeText, 10)
+		if !ok {
+			slog.Warn("Could not parse delegated stake of validator", "validator_id", validatorId, "stake", delegatedStakeText)
+			continue
+		}
+
+		results[validatorId] = utils.ValidatorStake{Stake: stake, DelegatedStake: delegatedStake, CommissionRate: commissionRate}
+	}
+
+	return results, nil
+}
+
+// GetCheckpointReward gets the block number, proposer id and reward in wei of
+// the passed checkpoint. The reward is nil if it was not stored in full.
+func GetCheckpointReward(checkpointNumber int) (uint64, int, *big.Int, error) {
+	defer telemetry.ObserveDBQuery("GetCheckpointReward", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, 0, nil, err
+	}
+	defer db.Close()
+
+	var blockNumber uint64
+	var proposerId int
+	var rewardText sql.NullString
+	err = db.QueryRow(`SELECT block_number, proposer_id, reward_wei
+			FROM checkpoints
+			WHERE number = ?`, checkpointNumber).Scan(&blockNumber, &proposerId, &rewardText)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "checkpoint not found while trying to get its reward"}}
+	} else if err != nil {
+		slog.Error("Error while querying for checkpoint reward", "checkpoint", checkpointNumber, "error", err)
+		return 0, 0, nil, err
+	}
+
+	if !rewardText.Valid {
+		return blockNumber, proposerId, nil, nil
+	}
+	reward, ok := new(big.Int).SetString(rewardText.String, 10)
+	if !ok {
+		slog.Warn("Could not parse reward of checkpoint", "checkpoint", checkpointNumber, "reward", rewardText.String)
+		return blockNumber, proposerId, nil, nil
+	}
+
+	return blockNumber, proposerId, reward, nil
+}
+
+// SaveEstimatedRewards saves the estimated rewards of the passed checkpoint,
+// replacing the ones previously saved for the same validators.
+func SaveEstimatedRewards(checkpointNumber int, rewards []utils.EstimatedReward) error {
+	defer telemetry.ObserveDBQuery("SaveEstimatedRewards", time.Now())
+
+	if len(rewards) == 0 {
+		return nil
+	}
+
+	// get checkpoint id from database
+	checkpointId, err := getCheckpointId(uint64(checkpointNumber))
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
+	}
+
+	statement, err := tx.Prepare(`INSERT OR REPLACE INTO estimated_rewards(checkpoint_id, validator_id, reward, delegator_reward, delegator_reward_per_pol, missed_reward)
+			VALUES(?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
+
+	for _, reward := range rewards {
+		_, err = statement.Exec(checkpointId, reward.ValidatorId, reward.Reward.String(), reward.DelegatorReward.String(), reward.DelegatorRewardPerPOL.String(), reward.MissedReward.String())
+		if err != nil {
+			slog.Error("Error while saving estimated reward", "checkpoint", checkpointNumber, "validator_id", reward.ValidatorId, "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.Error("Error while committing estimated rewards", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetEstimatedRewardsInRange gets the sum of the estimated rewards of each
+// validator over the checkpoints within the range provided, keyed by
+// validator id.
+func GetEstimatedRewardsInRange(startNumber int, endNumber int) (map[int]utils.EstimatedReward, error) {
+	defer telemetry.ObserveDBQuery("GetEstimatedRewardsInRange", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	// the rewards are summed here rather than in SQL, as they are stored as
+	// text to not lose precision
+	selectSQL := `SELECT r.validator_id, r.reward, r.delegator_reward, COALESCE(r.delegator_reward_per_pol, '0'), r.missed_reward
+			FROM estimated_rewards r
+			LEFT JOIN checkpoints c
+			ON r.checkpoint_id = c.id
+			WHERE c.number >= ?
+			AND c.number <= ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for estimated rewards in range", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]utils.EstimatedReward{}
+	for rows.Next() {
+		var validatorId int
+		var values [4]string
+
+		err = rows.Scan(&validatorId, &values[0], &values[1], &values[2], &values[3])
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		reward := utils.NewEstimatedReward(validatorId)
+		for i, target := range []*big.Int{reward.Reward, reward.DelegatorReward, reward.DelegatorRewardPerPOL, reward.MissedReward} {
+			if _, ok := target.SetString(values[i], 10); !ok {
+				slog.Warn("Could not parse estimated reward", "validator_id", validatorId, "value", values[i])
+			}
+		}
+
+		total, found := results[validatorId]
+		if !found {
+			total = utils.NewEstimatedReward(validatorId)
+			results[validatorId] = total
+		}
+		total.Add(reward)
+	}
+
+	return results, nil
+}
+
+// AddPendingRewards saves the passed checkpoint as one whose rewards still have
+// to be estimated.
+func AddPendingRewards(checkpointNumber int) error {
+	defer telemetry.ObserveDBQuery("AddPendingRewards", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT OR IGNORE INTO pending_rewards(checkpoint_number)
+			VALUES(?)`, checkpointNumber)
+	if err != nil {
+		slog.Error("Error while saving pending rewards", "checkpoint", checkpointNumber, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetPendingRewards gets the numbers of the checkpoints whose rewards still
+// have to be estimated, in ascending order.
+func GetPendingRewards() ([]int, error) {
+	defer telemetry.ObserveDBQuery("GetPendingRewards", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT checkpoint_number
+			FROM pending_rewards
+			ORDER BY checkpoint_number`)
+	if err != nil {
+		slog.Error("Error while querying for pending rewards", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpointNumbers := []int{}
+	for rows.Next() {
+		var checkpointNumber int
+
+		err = rows.Scan(&checkpointNumber)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		checkpointNumbers = append(checkpointNumbers, checkpointNumber)
+	}
+
+	return checkpointNumbers, nil
+}
+
+// DeletePendingRewards removes the passed checkpoint from the ones whose
+// rewards still have to be estimated.
+func DeletePendingRewards(checkpointNumber int) error {
+	defer telemetry.ObserveDBQuery("DeletePendingRewards", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`DELETE FROM pending_rewards
+			WHERE checkpoint_number = ?`, checkpointNumber)
+	if err != nil {
+		slog.Error("Error while deleting pending rewards", "checkpoint", checkpointNumber, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// InsertFailedSubmissions saves the passed failed submissions, attributing
+// each one to the validator whose signer sent it. Submissions sent by an
+// unknown signer are saved without a validator id, and the ones already saved
+// are ignored.
+func InsertFailedSubmissions(submissions []utils.FailedSubmission) error {
+	defer telemetry.ObserveDBQuery("InsertFailedSubmissions", time.Now())
+
+	if len(submissions) == 0 {
+		return nil
+	}
+
+	// resolve the validator ids first, as each lookup opens its own connection
+	validatorIds := make([]sql.NullInt64, len(submissions))
+	for i, submission := range submissions {
+		validatorId, err := getValidatorIdDB(submission.Sender.String())
+		if err != nil {
+			switch err.(type) {
+			case *utils.ValidatorNotFoundError:
+				slog.Warn("Could not find validator with signer key of failed submission in database", "tx_hash", submission.TxHash, "sender", submission.Sender)
+				continue
+			default:
+				return err
+			}
+		}
+		validatorIds[i] = sql.NullInt64{Int64: int64(validatorId), Valid: true}
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error while starting database transaction", "error", err)
+		return err
+	}
+
+	statement, err := tx.Prepare(`INSERT OR IGNORE INTO failed_submissions(tx_hash, block_number, timestamp, sender, validator_id, gas_used, effective_gas_price, cost_wei, revert_reason)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
+
+	for i, submission := range submissions {
+		_, err = statement.Exec(submission.TxHash.String(), submission.BlockNumber, submission.Timestamp, submission.Sender.String(), validatorIds[i],
+			submission.Cost.GasUsed, submission.Cost.EffectiveGasPrice.String(), submission.Cost.Cost.String(), submission.RevertReason)
+		if err != nil {
+			slog.Error("Error while saving failed submission", "tx_hash", submission.TxHash, "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		slog.Error("Error while committing failed submissions", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetFailedSubmissionStats gets the number of failed submissions, the ETH they
+// burned and the timestamp of the last one, keyed by the id of the validator
+// which sent them. Submissions sent by an unknown signer are counted under 0.
+func GetFailedSubmissionStats() (map[int]utils.FailedSubmissionStats, error) {
+	defer telemetry.ObserveDBQuery("GetFailedSubmissionStats", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	// the costs are summed here rather than in SQL, as they are stored as
+	// text to not lose precision
+	rows, err := db.Query(`SELECT COALESCE(validator_id, 0), timestamp, cost_wei
+			FROM failed_submissions`)
+	if err != nil {
+		slog.Error("Error while querying for failed submissions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int]utils.FailedSubmissionStats{}
+	for rows.Next() {
+		var validatorId int
+		var timestamp uint64
+		var costText string
+
+		err = rows.Scan(&validatorId, &timestamp, &costText)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		stats, found := results[validatorId]
+		if !found {
+			stats = utils.FailedSubmissionStats{Cost: new(big.Int)}
+		}
+		stats.Count++
+		stats.LastTimestamp = max(stats.LastTimestamp, timestamp)
+		if cost, ok := new(big.Int).SetString(costText, 10); ok {
+			stats.Cost.Add(stats.Cost, cost)
+		} else {
+			slog.Warn("Could not parse cost of failed submission", "validator_id", validatorId, "cost", costText)
+		}
+		results[validatorId] = stats
+	}
+
+	return results, nil
+}
+
+// GetFailedSubmissionsScanBlock gets the next block to look for failed
+// submissions in. The second return value is false if no block was scanned
+// yet.
+func GetFailedSubmissionsScanBlock() (uint64, bool, error) {
+	defer telemetry.ObserveDBQuery("GetFailedSubmissionsScanBlock", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, false, err
+	}
+	defer db.Close()
+
+	var nextBlock uint64
+	err = db.QueryRow(`SELECT next_block
+			FROM failed_submissions_scan
+			WHERE id = 1`).Scan(&nextBlock)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		slog.Error("Error while querying for failed submissions scan block", "error", err)
+		return 0, false, err
+	}
+
+	return nextBlock, true, nil
+}
+
+// SaveFailedSubmissionsScanBlock saves the next block to look for failed
+// submissions in, replacing the one previously saved.
+func SaveFailedSubmissionsScanBlock(nextBlock uint64) error {
+	defer telemetry.ObserveDBQuery("SaveFailedSubmissionsScanBlock", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT OR REPLACE INTO failed_submissions_scan(id, next_block)
+			VALUES(1, ?)`, nextBlock)
+	if err != nil {
+		slog.Error("Error while saving failed submissions scan block", "block", nextBlock, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// the validator IDs each selector is known to be saved as resolving to, keyed
+// by selector, so that they are only written when they change
+var (
+	savedSelectorsMutex sync.Mutex
+	savedSelectors      = map[string][]int{}
+)
+
+// the IDs the tracked validators last resolved to, so that they can be read
+// without resolving them again
+var (
+	resolvedTrackedMutex sync.Mutex
+	resolvedTrackedIds   []int
+)
+
+// ResolveTrackedValidators returns the IDs of the validators being tracked, as
+// specified in the config. Owner and signer keys are resolved through the
+// validators table. Once resolved, the ID is remembered, so that the validator
+// is still tracked after it changes its keys.
+func ResolveTrackedValidators() ([]int, error) {
+	validatorIds, err := resolveTrackedValidators(true)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedTrackedMutex.Lock()
+	resolvedTrackedIds = validatorIds
+	resolvedTrackedMutex.Unlock()
+
+	return validatorIds, nil
+}
+
+// GetTrackedValidators returns the IDs of the validators being tracked, as they
+// were last resolved by ResolveTrackedValidators. It never writes to the
+// database, so it can be used outside of the sync loop. If the tracked
+// validators have not been resolved yet, they are resolved without remembering
+// what their keys resolved to.
+func GetTrackedValidators() ([]int, error) {
+	resolvedTrackedMutex.Lock()
+	validatorIds := resolvedTrackedIds
+	resolvedTrackedMutex.Unlock()
+
+	if validatorIds != nil {
+		return validatorIds, nil
+	}
+	return resolveTrackedValidators(false)
+}
+
+// resolveTrackedValidators resolves the IDs of the validators being tracked.
+// If remember is true, what each key resolved to is saved in the database.
+func resolveTrackedValidators(remember bool) ([]int, error) {
+	defer telemetry.ObserveDBQuery("ResolveTrackedValidators", time.Now())
+
+	if utils.CheckIfTrackAll() {
+		return getAllValidatorIds()
+	}
+
+	validatorIds := []int{}
+	for _, tracked := range utils.TrackedValidators() {
+		var ids []int
+		var err error
+
+		switch {
+		case tracked.ID != 0:
+			ids = []int{tracked.ID}
+		case tracked.Owner != "":
+			ids, err = getValidatorIdsByKey("owner_key", tracked.Owner)
+		default:
+			ids, err = getValidatorIdsByKey("signer_key", tracked.Signer)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ids) > 0 {
+			// remember what the key resolved to
+			if remember {
+				err = rememberTrackedValidator(tracked.String(), ids)
+				if err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			// the key is no longer in the validators table, most likely because
+			// it was changed, so use the validator it last resolved to
+			ids, err = getSavedTrackedValidator(tracked.String())
+			if err != nil {
+				return nil, err
+			}
+			if len(ids) == 0 {
+				slog.Warn("Could not find tracked validator in database", "validator", tracked.String())
+			}
+		}
+
+		for _, id := range ids {
+			if !utils.Contains(validatorIds, id) {
+				validatorIds = append(validatorIds, id)
+			}
+		}
+	}
+
+	return validatorIds, nil
+}
+
+// queryValidatorIds runs the passed query, which must select validator IDs,
+// and returns the results.
+func queryValidatorIds(selectSQL string, args ...any) ([]int, error) {
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(args...)
+	if err != nil {
+		slog.Error("Error while querying for validator ids", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	validatorIds := []int{}
+	for rows.Next() {
+		var validatorId int
+
+		err = rows.Scan(&validatorId)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return nil, err
+		}
+
+		validatorIds = append(validatorIds, validatorId)
+	}
+
+	return validatorIds, nil
+}
+
+// getAllValidatorIds gets the IDs of all the validators in the database,
+// except for the blank validator.
+func getAllValidatorIds() ([]int, error) {
+	selectSQL := `SELECT id
+			FROM validators
+			WHERE id > 0
+			ORDER BY id`
+
+	return queryValidatorIds(selectSQL)
+}
+
+// getValidatorIdsByKey gets the IDs of the validators whose key in the passed
+// column (owner_key or signer_key) matches the passed key. An owner can own
+// more than one validator.
+func getValidatorIdsByKey(column string, key string) ([]int, error) {
+	selectSQL := `SELECT id
+			FROM validators
+			WHERE id > 0
+			AND ` + column + ` LIKE ?
+			ORDER BY id`
+
+	return queryValidatorIds(selectSQL, key)
+}
+
+// getSavedTrackedValidator gets the IDs of the validators the passed selector
+// last resolved to.
+func getSavedTrackedValidator(selector string) ([]int, error) {
+	selectSQL := `SELECT validator_id
+			FROM tracked_validators
+			WHERE selector = ?`
+
+	return queryValidatorIds(selectSQL, strings.ToLower(selector))
+}
+
+// rememberTrackedValidator remembers that the passed selector resolved to the
+// passed validator IDs. Only the IDs which are not saved for the selector yet
+// are written, so that nothing is written while the resolved set stays the
+// same.
+func rememberTrackedValidator(selector string, validatorIds []int) error {
+	savedSelectorsMutex.Lock()
+	defer savedSelectorsMutex.Unlock()
+
+	saved, found := savedSelectors[selector]
+	if !found {
+		var err error
+		saved, err = getSavedTrackedValidator(selector)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, validatorId := range validatorIds {
+		if utils.Contains(saved, validatorId) {
+			continue
+		}
+
+		err := saveTrackedValidator(selector, validatorId)
+		if err != nil {
+			return err
+		}
+		saved = append(saved, validatorId)
+	}
+	savedSelectors[selector] = saved
+
+	return nil
+}
+
+// saveTrackedValidator remembers that the passed selector (e.g. signer:0x..)
+// resolved to the passed validator ID.
+func saveTrackedValidator(selector string, validatorId int) error {
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	insertSQL := `INSERT OR REPLACE INTO tracked_validators(selector, validator_id)
+			VALUES(?, ?)`
+
+	statement, err := db.Prepare(insertSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(strings.ToLower(selector), validatorId)
+	if err != nil {
+		slog.Error("Error while saving tracked validator", "validator_id", validatorId, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -3,11 +3,11 @@ package database
 import (
 	"database/sql"
 	"errors"
-	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
+	"monitor/internal/telemetry"
 	"monitor/internal/utils"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -21,10 +21,12 @@ import (
 // GetValidator gets the validator by its id, and returns all the information
 // in a Validator struct.
 func GetValidator(validatorId int) (utils.Validator, error) {
+	defer telemetry.ObserveDBQuery("GetValidator", time.Now())
+
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return utils.Validator{}, err
 	}
 	defer db.Close()
@@ -36,7 +38,7 @@ func GetValidator(validatorId int) (utils.Validator, error) {
 	// prepare the SQL statement
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return utils.Validator{}, err
 	}
 	defer statement.Close()
@@ -44,7 +46,7 @@ func GetValidator(validatorId int) (utils.Validator, error) {
 	// query the database
 	rows, err := statement.Query(validatorId)
 	if err != nil {
-		fmt.Printf("ERR: Error while checking if validator exists in db, error: %v\n", err)
+		slog.Error("Error while checking if validator exists in db", "error", err)
 		return utils.Validator{}, err
 	}
 	defer rows.Close()
@@ -60,7 +62,7 @@ func GetValidator(validatorId int) (utils.Validator, error) {
 		// populate the variables
 		err = rows.Scan(&validatorId, &ownerKey, &signerKey, &activationEpoch, &deactivationEpoch)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return utils.Validator{}, err
 		}
 
@@ -84,9 +86,9 @@ func GetValidator(validatorId int) (utils.Validator, error) {
 // the database.
 func getMaxValidatorId() (int, error) {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -97,7 +99,7 @@ func getMaxValidatorId() (int, error) {
 	// prepare the SQL query
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
@@ -105,7 +107,7 @@ func getMaxValidatorId() (int, error) {
 	// query the database
 	rows, err := statement.Query()
 	if err != nil {
-		fmt.Printf("ERR: Error while getting maximum validator id from database, error: %v\n", err)
+		slog.Error("Error while getting maximum validator id from database", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -116,7 +118,7 @@ func getMaxValidatorId() (int, error) {
 		// store the value in the variable
 		err = rows.Scan(&maxValId)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 		if maxValId.Valid {
@@ -135,9 +137,9 @@ func getMaxValidatorId() (int, error) {
 // getValidatorIdDB gets the ID of the validator with the provided signer key.
 func getValidatorIdDB(signerKey string) (int, error) {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return 0, err
 	}
 	defer db.Close()
@@ -149,7 +151,7 @@ func getValidatorIdDB(signerKey string) (int, error) {
 	// prepare the SQL statement
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return 0, err
 	}
 	defer statement.Close()
@@ -157,7 +159,7 @@ func getValidatorIdDB(signerKey string) (int, error) {
 	// query the database
 	rows, err := statement.Query(signerKey)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for validator id using public key, error: %v\n", err)
+		slog.Error("Error while querying for validator id using public key", "error", err)
 		return 0, err
 	}
 	defer rows.Close()
@@ -168,7 +170,7 @@ func getValidatorIdDB(signerKey string) (int, error) {
 		// get the id
 		err = rows.Scan(&id)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return 0, err
 		}
 
@@ -176,63 +178,16 @@ func getValidatorIdDB(signerKey string) (int, error) {
 	}
 
 	// in case of no rows, return relevant error
-	fmt.Printf("ERR: Could not find validator with public key %v in database.\n", signerKey)
+	slog.Debug("Could not find validator with public key in database", "signer", signerKey)
 	return 0, &utils.ValidatorNotFoundError{GenericError: utils.GenericError{Message: "validator with provided pubkey not found"}}
 }
 
-// getAllValidatorsSignerKeys gets all the signer keys of all the validators in
-// the database.
-func getAllValidatorsSignerKeys() ([]string, error) {
-	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
-	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
-		return nil, err
-	}
-	defer db.Close()
-
-	selectSQL := `SELECT signer_key
-			FROM validators`
-
-	// prepare the SQL query
-	statement, err := db.Prepare(selectSQL)
-	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
-		return nil, err
-	}
-	defer statement.Close()
-
-	// query the database
-	rows, err := statement.Query()
-	if err != nil {
-		fmt.Printf("ERR: Error while querying for validators, error: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []string // slice to hold signer keys
-	for rows.Next() {
-		var publicKey string
-
-		err = rows.Scan(&publicKey)
-		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
-			return nil, err
-		}
-
-		// append each signer key to slice
-		results = append(results, publicKey)
-	}
-
-	return results, nil
-}
-
 // insertValidator inserts the passed validator in the database.
 func insertValidator(validator utils.Validator) error {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -244,7 +199,7 @@ func insertValidator(validator utils.Validator) error {
 	// prepare the SQL
 	statement, err := db.Prepare(insertSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
@@ -252,7 +207,7 @@ func insertValidator(validator utils.Validator) error {
 	// execute the SQL
 	_, err = statement.Exec(validator.ValidatorId, validator.OwnerAddress.String(), validator.SignerAddress.String(), validator.ActivationEpoch, validator.DeactivationEpoch)
 	if err != nil {
-		fmt.Printf("ERR: Error while executing validator insert, error: %v\n", err)
+		slog.Error("Error while executing validator insert", "validator_id", validator.ValidatorId, "error", err)
 		return err
 	}
 
@@ -262,9 +217,9 @@ func insertValidator(validator utils.Validator) error {
 // updateValidator updates the passed validator in the database.
 func updateValidator(validator utils.Validator) error {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -277,7 +232,7 @@ func updateValidator(validator utils.Validator) error {
 	// prepare the SQL
 	statement, err := db.Prepare(updateSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
@@ -285,7 +240,7 @@ func updateValidator(validator utils.Validator) error {
 	// execute the SQL
 	_, err = statement.Exec(validator.OwnerAddress.String(), validator.SignerAddress.String(), validator.ActivationEpoch, validator.DeactivationEpoch, validator.ValidatorId)
 	if err != nil {
-		fmt.Printf("ERR: Error while executing validator insert, error: %v\n", err)
+		slog.Error("Error while executing validator insert", "error", err)
 		return err
 	}
 
@@ -297,9 +252,9 @@ func updateValidator(validator utils.Validator) error {
 // would be the proposer of that checkpoint.
 func insertBlankValidator() error {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return err
 	}
 	defer db.Close()
@@ -311,7 +266,7 @@ func insertBlankValidator() error {
 	// prepare the SQL
 	statement, err := db.Prepare(insertSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return err
 	}
 	defer statement.Close()
@@ -319,7 +274,7 @@ func insertBlankValidator() error {
 	// execute the statement
 	_, err = statement.Exec()
 	if err != nil {
-		fmt.Printf("ERR: Error while executing validator insert, error: %v\n", err)
+		slog.Error("Error while executing validator insert", "error", err)
 		return err
 	}
 
@@ -330,9 +285,9 @@ func insertBlankValidator() error {
 // signed checkpoints table for the given checkpoint.
 func checkIfValidatorInSigned(checkpointId int, validatorId int) (bool, error) {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return false, err
 	}
 	defer db.Close()
@@ -344,7 +299,7 @@ func checkIfValidatorInSigned(checkpointId int, validatorId int) (bool, error) {
 	// prepare the SQL
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return false, err
 	}
 	defer statement.Close()
@@ -352,7 +307,7 @@ func checkIfValidatorInSigned(checkpointId int, validatorId int) (bool, error) {
 	// query the database
 	rows, err := statement.Query(checkpointId, validatorId)
 	if err != nil {
-		fmt.Printf("ERR: Error while checking if validators exists in signed db, error: %v\n", err)
+		slog.Error("Error while checking if validators exists in signed db", "error", err)
 		return false, err
 	}
 	defer rows.Close()
@@ -362,7 +317,7 @@ func checkIfValidatorInSigned(checkpointId int, validatorId int) (bool, error) {
 
 		err = rows.Scan(&id)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return false, err
 		}
 		if id.Valid {
@@ -381,9 +336,9 @@ func checkIfValidatorInSigned(checkpointId int, validatorId int) (bool, error) {
 // signed checkpoints table for the given checkpoint.
 func checkIfValidatorInTemp(checkpointId int, validatorId int) (bool, error) {
 	// open the database
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return false, err
 	}
 	defer db.Close()
@@ -395,7 +350,7 @@ func checkIfValidatorInTemp(checkpointId int, validatorId int) (bool, error) {
 	// prepare the SQL
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return false, err
 	}
 	defer statement.Close()
@@ -403,7 +358,7 @@ func checkIfValidatorInTemp(checkpointId int, validatorId int) (bool, error) {
 	// query the database
 	rows, err := statement.Query(checkpointId, validatorId)
 	if err != nil {
-		fmt.Printf("ERR: Error while checking if validators exists in temporary db, error: %v\n", err)
+		slog.Error("Error while checking if validators exists in temporary db", "error", err)
 		return false, err
 	}
 	defer rows.Close()
@@ -413,7 +368,7 @@ func checkIfValidatorInTemp(checkpointId int, validatorId int) (bool, error) {
 
 		err = rows.Scan(&id)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return false, err
 		}
 		if id.Valid {
@@ -431,9 +386,11 @@ func checkIfValidatorInTemp(checkpointId int, validatorId int) (bool, error) {
 // getDeactivatedValidators returns IDs of validators whose deactivation epoch
 // is smaller than the passed epoch (checkpoint).
 func getDeactivatedValidators(checkpoint int) ([]int, error) {
-	db, err := sql.Open("sqlite3", utils.Config.DatabaseLocation)
+	defer telemetry.ObserveDBQuery("GetDeactivatedValidators", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
 	if err != nil {
-		fmt.Printf("ERR: Could not open database, error: %v\n", err)
+		slog.Error("Could not open database", "error", err)
 		return nil, err
 	}
 	defer db.Close()
@@ -447,7 +404,7 @@ func getDeactivatedValidators(checkpoint int) ([]int, error) {
 	// prepare the SQL
 	statement, err := db.Prepare(selectSQL)
 	if err != nil {
-		fmt.Printf("ERR: Error while preparing SQL statement, error: %v\n", err)
+		slog.Error("Error while preparing SQL statement", "error", err)
 		return nil, err
 	}
 	defer statement.Close()
@@ -455,7 +412,7 @@ func getDeactivatedValidators(checkpoint int) ([]int, error) {
 	// query the database
 	rows, err := statement.Query(checkpoint)
 	if err != nil {
-		fmt.Printf("ERR: Error while querying for maximum block number, error: %v\n", err)
+		slog.Error("Error while querying for maximum block number", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -466,7 +423,7 @@ func getDeactivatedValidators(checkpoint int) ([]int, error) {
 
 		err = rows.Scan(&validatorId)
 		if err != nil {
-			fmt.Printf("ERR: Error while reading row from db, error: %v\n", err)
+			slog.Error("Error while reading row from db", "error", err)
 			return nil, err
 		}
 
@@ -496,7 +453,7 @@ func getAndInsertValidators(blockNumber uint64, validatorStartingId int, deactiv
 
 	// try to reach the ETH node
 	for i := 0; i < utils.RETRIES; i++ {
-		ethRPCClient, err = rpc.Dial(utils.Config.ETHRpcUrl)
+		ethRPCClient, err = utils.DialETH()
 		if err == nil {
 			break
 		}
@@ -505,7 +462,7 @@ func getAndInsertValidators(blockNumber uint64, validatorStartingId int, deactiv
 	}
 
 	if err != nil {
-		log.Printf("ERR: Unable to dial ETH node (%s), error: %v\n", utils.Config.ETHRpcUrl, err)
+		slog.Error("Unable to dial ETH node", "url", utils.RedactURL(utils.GetConfig().ETHRpcUrl), "error", err)
 		return &utils.DialError{GenericError: utils.GenericError{Message: "unable to dial ETH node"}}
 	}
 	ethClient := ethclient.NewClient(ethRPCClient)
@@ -516,7 +473,7 @@ func getAndInsertValidators(blockNumber uint64, validatorStartingId int, deactiv
 
 	// get StakeManager ABI to decode encode query and decode response
 	if ccAbi, err := utils.GetABI(stakemanager.StakemanagerABI); err != nil {
-		log.Printf("ERR: Error while fetching StakeManager ABI, error: %v\n", err)
+		slog.Error("Error while fetching StakeManager ABI", "error", err)
 		return errors.New("unable to fetch StakeManager ABI")
 	} else {
 		stakeManagerABI = ccAbi
@@ -606,20 +563,32 @@ func getAndInsertValidators(blockNumber uint64, validatorStartingId int, deactiv
 		}
 	}
 
+	return saveValidators(validators, blockNumber)
+}
+
+// saveValidators inserts or updates the passed validators, fetched at the
+// passed block, in the database, along with their stake.
+func saveValidators(validators []utils.Validator, blockNumber uint64) error {
+	defer telemetry.ObserveDBQuery("SaveValidators", time.Now())
+
 	// insert or update the validators table in the database
 	for _, validator := range validators {
-		err = insertOrUpdateValidator(validator)
+		err := insertOrUpdateValidator(validator)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	// keep the stake of each validator, which changes far more often than
+	// the rest of its fields
+	return saveValidatorStakes(validators, blockNumber)
 }
 
 // ValidatorTableEmpty checks if the validators table is empty or not. It
 // assumes that it is not empty if validator with ID 1 is in the table.
 func ValidatorTableEmpty() (bool, error) {
+	defer telemetry.ObserveDBQuery("ValidatorTableEmpty", time.Now())
+
 	_, err := GetValidator(1)
 	if err != nil {
 		switch err.(type) {
@@ -672,46 +641,33 @@ func insertOrUpdateValidator(validator utils.Validator) error {
 				return nil
 			}
 
-			// print out what we are updating
-			fmt.Printf("INFO: Validator with ID %d is being updated: ", validator.ValidatorId)
-			if validator.OwnerAddress != validatorDB.OwnerAddress {
-				fmt.Printf("owner address is different; ")
-			}
-
-			if validator.SignerAddress != validatorDB.SignerAddress {
-				fmt.Printf("signer address is different; ")
-			}
-
-			if validator.DeactivationEpoch != validatorDB.DeactivationEpoch {
-				fmt.Printf("deactivation epoch is different;")
-			}
-			fmt.Println()
+			// log what we are updating
+			logValidatorUpdate(validator, validatorDB)
 
 			return updateValidator(tempValidator)
 		} else {
-			// print out what we are updating
-			fmt.Printf("INFO: Validator with ID %d is being updated: ", validator.ValidatorId)
-			if validator.OwnerAddress != validatorDB.OwnerAddress {
-				fmt.Printf("owner address is different; ")
-			}
-
-			if validator.SignerAddress != validatorDB.SignerAddress {
-				fmt.Printf("signer address is different; ")
-			}
-
-			if validator.DeactivationEpoch != validatorDB.DeactivationEpoch {
-				fmt.Printf("deactivation epoch is different;")
-			}
-			fmt.Println()
+			// log what we are updating
+			logValidatorUpdate(validator, validatorDB)
 			return updateValidator(validator)
 		}
 	}
 }
 
+// logValidatorUpdate logs which of the fields of a validator are being
+// updated in the database.
+func logValidatorUpdate(validator utils.Validator, validatorDB utils.Validator) {
+	slog.Info("Validator is being updated",
+		"validator_id", validator.ValidatorId,
+		"owner_changed", validator.OwnerAddress != validatorDB.OwnerAddress,
+		"signer_changed", validator.SignerAddress != validatorDB.SignerAddress,
+		"deactivation_epoch_changed", validator.DeactivationEpoch != validatorDB.DeactivationEpoch)
+}
+
 // UpdateValidatorsDB gets a list of the deactivated validators and then
-// passes it to the function that inserts and updates validators.
+// passes it to the function that inserts and updates validators. Most of its
+// time is spent calling the StakeManager, which is timed as RPC calls, so only
+// its queries are timed as database functions.
 func UpdateValidatorsDB(blockNumber uint64, checkpointNumber uint64) error {
-
 	deactivatedVals := []int{}
 	var err error
 	// if the checkpointNumber is 0, it implies that the validators table does
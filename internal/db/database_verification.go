@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SaveCheckpointVerification saves the passed verification result, replacing
+// the one previously saved for the same checkpoint.
+func SaveCheckpointVerification(verification utils.CheckpointVerification) error {
+	defer telemetry.ObserveDBQuery("SaveCheckpointVerification", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	insertSQL := `INSERT OR REPLACE INTO checkpoint_verification(checkpoint_number, bor_start_block, bor_end_block, submitted_root_hash, computed_root_hash, match, verified_at)
+			VALUES(?, ?, ?, ?, ?, ?, ?)`
+
+	statement, err := db.Prepare(insertSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(verification.CheckpointNumber, verification.StartBlock, verification.EndBlock, verification.SubmittedRootHash.String(),
+		verification.ComputedRootHash.String(), verification.Match, time.Now().Unix())
+	if err != nil {
+		slog.Error("Error while saving checkpoint verification", "checkpoint", verification.CheckpointNumber, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetVerificationStats gets the number of checkpoints verified, the number of
+// those whose root hash did not match, and the number of the last checkpoint
+// verified along with whether it matched. The last checkpoint is 0 if none
+// were verified.
+func GetVerificationStats() (int, int, int, bool, error) {
+	defer telemetry.ObserveDBQuery("GetVerificationStats", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, 0, 0, false, err
+	}
+	defer db.Close()
+
+	var verified, mismatches int
+	err = db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(1 - match), 0)
+			FROM checkpoint_verification`).Scan(&verified, &mismatches)
+	if err != nil {
+		slog.Error("Error while querying for checkpoint verifications", "error", err)
+		return 0, 0, 0, false, err
+	}
+	if verified == 0 {
+		return 0, 0, 0, false, nil
+	}
+
+	var lastCheckpoint int
+	var lastMatch bool
+	err = db.QueryRow(`SELECT checkpoint_number, match
+			FROM checkpoint_verification
+			ORDER BY checkpoint_number DESC
+			LIMIT 1`).Scan(&lastCheckpoint, &lastMatch)
+	if err != nil {
+		slog.Error("Error while querying for last checkpoint verification", "error", err)
+		return 0, 0, 0, false, err
+	}
+
+	return verified, mismatches, lastCheckpoint, lastMatch, nil
+}
+
+// GetVerificationScanCheckpoint gets the next checkpoint to verify. The second
+// return value is false if no checkpoint was verified yet.
+func GetVerificationScanCheckpoint() (int, bool, error) {
+	defer telemetry.ObserveDBQuery("GetVerificationScanCheckpoint", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, false, err
+	}
+	defer db.Close()
+
+	var nextCheckpoint int
+	err = db.QueryRow(`SELECT next_checkpoint
+			FROM checkpoint_verification_scan
+			WHERE id = 1`).Scan(&nextCheckpoint)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		slog.Error("Error while querying for checkpoint verification scan checkpoint", "error", err)
+		return 0, false, err
+	}
+
+	return nextCheckpoint, true, nil
+}
+
+// SaveVerificationScanCheckpoint saves the next checkpoint to verify,
+// replacing the one previously saved.
+func SaveVerificationScanCheckpoint(nextCheckpoint int) error {
+	defer telemetry.ObserveDBQuery("SaveVerificationScanCheckpoint", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT OR REPLACE INTO checkpoint_verification_scan(id, next_checkpoint)
+			VALUES(1, ?)`, nextCheckpoint)
+	if err != nil {
+		slog.Error("Error while saving checkpoint verification scan checkpoint", "checkpoint", nextCheckpoint, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"monitor/internal/telemetry"
+	"monitor/internal/utils"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// GetCheckpointTimestamp gets the timestamp of the block in which the passed
+// checkpoint was included.
+func GetCheckpointTimestamp(checkpointNumber int) (uint64, error) {
+	defer telemetry.ObserveDBQuery("GetCheckpointTimestamp", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT timestamp
+			FROM checkpoints
+			WHERE number = ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return 0, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(checkpointNumber)
+	if err != nil {
+		slog.Error("Error while querying for checkpoint timestamp", "checkpoint", checkpointNumber, "error", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timestamp uint64
+
+		err = rows.Scan(&timestamp)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return 0, err
+		}
+		return timestamp, nil
+	}
+
+	return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "checkpoint not found while trying to get its timestamp"}}
+}
+
+// getFirstCheckpointSince gets the number of the first checkpoint included in
+// a block with a timestamp equal to or after the passed one.
+func getFirstCheckpointSince(timestamp uint64) (int, error) {
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, err
+	}
+	defer db.Close()
+
+	selectSQL := `SELECT MIN(number)
+			FROM checkpoints
+			WHERE timestamp >= ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return 0, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(timestamp)
+	if err != nil {
+		slog.Error("Error while querying for first checkpoint since timestamp", "timestamp", timestamp, "error", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number sql.NullInt64
+
+		err = rows.Scan(&number)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return 0, err
+		}
+		if number.Valid {
+			return int(number.Int64), nil
+		}
+	}
+
+	return 0, &utils.CheckpointNotFoundError{GenericError: utils.GenericError{Message: "no checkpoint found after the given timestamp"}}
+}
+
+// GetWindowStart gets the number of the first checkpoint within the passed
+// performance window, ending at the passed checkpoint. Duration windows are
+// based on the timestamps of the checkpoints, and end at the timestamp of the
+// passed checkpoint.
+func GetWindowStart(window utils.PerformanceWindow, lastCheckpoint int) (int, error) {
+	defer telemetry.ObserveDBQuery("GetWindowStart", time.Now())
+
+	switch {
+	case window.Total:
+		return 0, nil
+	case window.Checkpoints > 0:
+		return lastCheckpoint - window.Checkpoints + 1, nil
+	}
+
+	lastTimestamp, err := GetCheckpointTimestamp(lastCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	windowSeconds := uint64(window.Duration.Seconds())
+	if windowSeconds >= lastTimestamp {
+		return 0, nil
+	}
+
+	// the window does not include the checkpoint exactly at its start, so
+	// that e.g. a 24h window never has more than a day's worth of checkpoints
+	return getFirstCheckpointSince(lastTimestamp - windowSeconds + 1)
+}
+
+// GetAverageCheckpointInterval gets the average number of seconds between
+// consecutive checkpoints, over the checkpoints within the range provided. It
+// returns 0 if there are less than two checkpoints in the range.
+func GetAverageCheckpointInterval(startNumber int, endNumber int) (float64, error) {
+	defer telemetry.ObserveDBQuery("GetAverageCheckpointInterval", time.Now())
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		slog.Error("Could not open database", "error", err)
+		return 0, err
+	}
+	defer db.Close()
+
+	// use the checkpoint numbers rather than the number of rows, so that
+	// checkpoints missing from the database do not skew the average
+	selectSQL := `SELECT MIN(number), MAX(number), MIN(timestamp), MAX(timestamp)
+			FROM checkpoints
+			WHERE number >= ? AND number <= ?`
+
+	statement, err := db.Prepare(selectSQL)
+	if err != nil {
+		slog.Error("Error while preparing SQL statement", "error", err)
+		return 0, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(startNumber, endNumber)
+	if err != nil {
+		slog.Error("Error while querying for checkpoint timestamps in range", "error", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var firstNumber, lastNumber, firstTimestamp, lastTimestamp sql.NullInt64
+
+		err = rows.Scan(&firstNumber, &lastNumber, &firstTimestamp, &lastTimestamp)
+		if err != nil {
+			slog.Error("Error while reading row from db", "error", err)
+			return 0, err
+		}
+		if !firstNumber.Valid || lastNumber.Int64 == firstNumber.Int64 {
+			return 0, nil
+		}
+
+		return float64(lastTimestamp.Int64-firstTimestamp.Int64) / float64(lastNumber.Int64-firstNumber.Int64), nil
+	}
+
+	return 0, nil
+}
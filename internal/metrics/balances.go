@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"math/big"
+	"time"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// balancesLastUpdated is when the ETH balance of the tracked signers was last
+// polled.
+var balancesLastUpdated time.Time
+
+// updateCostMetrics updates the gas used, gas price and cost of the
+// transaction which submitted the last checkpoint, along with the average
+// cost over the last 100 checkpoints.
+func updateCostMetrics(lastCheckpoint int) error {
+	costs, err := database.GetCheckpointCosts(lastCheckpoint-utils.CADENCE_CHECKPOINTS+1, lastCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	if cost, found := costs[lastCheckpoint]; found {
+		checkpointGasUsed.Set(float64(cost.GasUsed))
+		checkpointEffectiveGasPrice.Set(utils.WeiToETH(cost.EffectiveGasPrice) * 1e9)
+		checkpointCost.Set(utils.WeiToETH(cost.Cost))
+	}
+
+	if average := averageCost(costs); average != nil {
+		averageCheckpointCost.Set(utils.WeiToETH(average))
+	}
+
+	return nil
+}
+
+// averageCost returns the average of the passed costs, or nil if there are
+// none.
+func averageCost(costs map[int]utils.TransactionCost) *big.Int {
+	if len(costs) == 0 {
+		return nil
+	}
+
+	total := new(big.Int)
+	for _, cost := range costs {
+		total.Add(total, cost.Cost)
+	}
+	return total.Div(total, big.NewInt(int64(len(costs))))
+}
+
+// UpdateBalanceMetrics polls the ETH balance of the signer of every tracked
+// validator, and updates the metrics on whether it is running low and how many
+// checkpoints it can still submit. Unless force is true, balances are polled
+// at most once every BALANCE_INTERVAL seconds. A validator whose balance
+// cannot be polled does not stop the rest from being updated, and the errors
+// of all of them are returned together.
+func UpdateBalanceMetrics(force bool) error {
+	if !force && time.Since(balancesLastUpdated) < time.Second*utils.BALANCE_INTERVAL {
+		return nil
+	}
+
+	trackedIds, err := database.ResolveTrackedValidators()
+	if err != nil {
+		return err
+	}
+
+	var average *big.Int
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == nil {
+		costs, err := database.GetCheckpointCosts(lastCheckpoint-utils.CADENCE_CHECKPOINTS+1, lastCheckpoint)
+		if err != nil {
+			return err
+		}
+		average = averageCost(costs)
+	}
+
+	errs := []error{}
+	threshold := utils.LowBalanceThreshold()
+	for _, validatorId := range trackedIds {
+		validator, err := database.GetValidator(validatorId)
+		if err != nil {
+			slog.Warn("Could not get the signer of validator to poll its balance", "validator_id", validatorId, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		balance, err := utils.GetBalance(validator.SignerAddress)
+		if err != nil {
+			slog.Warn("Could not poll the ETH balance of signer", "validator_id", validatorId, "signer", validator.SignerAddress, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		id, signerKey, name := validatorLabels(validatorId)
+		balanceETH := utils.WeiToETH(balance)
+		signerBalance.WithLabelValues(id, signerKey, name).Set(balanceETH)
+
+		low := 0.0
+		if balanceETH < threshold {
+			low = 1
+		}
+		signerBalanceLow.WithLabelValues(id, signerKey, name).Set(low)
+
+		if average != nil && average.Sign() > 0 {
+			signerSubmissionsRemaining.WithLabelValues(id, signerKey, name).Set(math.Floor(balanceETH / utils.WeiToETH(average)))
+		}
+	}
+
+	balancesLastUpdated = time.Now()
+
+	return errors.Join(errs...)
+}
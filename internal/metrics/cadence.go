@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// lastIntervalObserved is the number of the last checkpoint whose interval
+// from the previous checkpoint was observed, so that a checkpoint processed
+// again is not observed twice.
+var lastIntervalObserved int
+
+// stalled is whether checkpointing was considered stalled on the last update,
+// so that only changes are logged.
+var stalled bool
+
+// ObserveCheckpointInterval observes the number of seconds between the passed
+// checkpoint, included in a block with the passed timestamp, and the previous
+// checkpoint. Nothing is observed if the previous checkpoint is not stored, or
+// the checkpoint was already observed.
+func ObserveCheckpointInterval(checkpointNumber int, timestamp uint64) error {
+	if checkpointNumber <= lastIntervalObserved {
+		return nil
+	}
+
+	previousTimestamp, err := database.GetCheckpointTimestamp(checkpointNumber - 1)
+	if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	if timestamp >= previousTimestamp {
+		checkpointInterval.Observe(float64(timestamp - previousTimestamp))
+	}
+	lastIntervalObserved = checkpointNumber
+
+	return nil
+}
+
+// UpdateStallMetrics updates the timestamp of the last checkpoint and the time
+// since it, and flags checkpointing as stalled if it has been longer than
+// StallMultiplier times the average interval between the last checkpoints. It
+// should be called periodically, even when no new checkpoints are processed.
+func UpdateStallMetrics() error {
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	lastTimestamp, err := database.GetCheckpointTimestamp(lastCheckpoint)
+	if err != nil {
+		return err
+	}
+	interval, err := database.GetAverageCheckpointInterval(lastCheckpoint-utils.CADENCE_CHECKPOINTS, lastCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	sinceLast := time.Since(time.Unix(int64(lastTimestamp), 0)).Seconds()
+	lastCheckpointTimestamp.Set(float64(lastTimestamp))
+	timeSinceLastCheckpoint.Set(sinceLast)
+
+	// the cadence is not known until there are at least two checkpoints
+	if interval <= 0 {
+		return nil
+	}
+
+	threshold := interval * utils.StallMultiplier()
+	stallThreshold.Set(threshold)
+
+	if sinceLast > threshold {
+		checkpointStalled.Set(1)
+		if !stalled {
+			slog.Warn("No checkpoint has landed for longer than usual, checkpointing might be stalled", "checkpoint", lastCheckpoint, "seconds_since", int(sinceLast), "threshold", int(threshold))
+		}
+		stalled = true
+	} else {
+		checkpointStalled.Set(0)
+		if stalled {
+			slog.Info("Checkpointing resumed", "checkpoint", lastCheckpoint)
+		}
+		stalled = false
+	}
+
+	return nil
+}
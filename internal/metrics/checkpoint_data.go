@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// updateCheckpointDataMetrics updates the number of Bor blocks covered by the
+// last checkpoint, and the last of them. Nothing is updated if the contents of
+// the last checkpoint are not stored.
+func updateCheckpointDataMetrics(lastCheckpoint int) error {
+	data, err := database.GetCheckpointData(lastCheckpoint)
+	if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	checkpointBorBlocks.Set(float64(data.BorBlocks()))
+	checkpointBorEndBlock.Set(float64(data.EndBlock))
+
+	return nil
+}
+
+// UpdateVerificationMetrics updates the number of checkpoints whose root hash
+// was verified, how many of them did not match, and whether the last one
+// matched. Nothing is updated until a checkpoint is verified.
+func UpdateVerificationMetrics() error {
+	verified, mismatches, lastCheckpoint, lastMatch, err := database.GetVerificationStats()
+	if err != nil {
+		return err
+	}
+	if lastCheckpoint == 0 {
+		return nil
+	}
+
+	checkpointsVerified.Set(float64(verified))
+	checkpointRootHashMismatches.Set(float64(mismatches))
+	if lastMatch {
+		checkpointRootHashMatch.Set(1)
+	} else {
+		checkpointRootHashMatch.Set(0)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// COUNTERS_VERIFY_INTERVAL is how often the running counters are verified
+// against a full recount. They are also verified on startup.
+const COUNTERS_VERIFY_INTERVAL = 24 * time.Hour
+
+// the running counters of each performance window, keyed by window name, along
+// with the last time they were verified
+var (
+	countersMutex        sync.Mutex
+	windowCounters       = map[string]utils.WindowCounters{}
+	countersLastVerified time.Time
+)
+
+// totalsMutex guards the running totals of the proposer and reward metrics,
+// which are only kept in memory.
+var totalsMutex sync.Mutex
+
+// ResetRunningTotals drops the running totals which are only kept in memory,
+// so that they are counted in full the next time they are needed.
+func ResetRunningTotals() {
+	totalsMutex.Lock()
+	defer totalsMutex.Unlock()
+
+	proposerWindows = map[string]proposerTotals{}
+	rewardWindows = map[string]rewardTotals{}
+}
+
+// countedWindows returns the performance windows for which running counters
+// are kept. These are the configured windows, plus the window of the last
+// PB_WINDOW checkpoints and the total window, which are always needed for the
+// performance benchmark and rank metrics.
+func countedWindows() []utils.PerformanceWindow {
+	windows := utils.PerformanceWindows()
+	hasPB, hasTotal := false, false
+	for _, window := range windows {
+		hasPB = hasPB || window.Checkpoints == utils.PB_WINDOW
+		hasTotal = hasTotal || window.Total
+	}
+	if !hasPB {
+		windows = append(windows, utils.PerformanceWindow{Name: "700", Checkpoints: utils.PB_WINDOW})
+	}
+	if !hasTotal {
+		windows = append(windows, utils.PerformanceWindow{Name: "total", Total: true})
+	}
+	return windows
+}
+
+// updateRunningTotals brings running totals, which cover the checkpoints from
+// start to end, up to date with a window which now covers the checkpoints from
+// newStart to newEnd. The checkpoints which entered the window are passed to
+// add, and the ones which left it to subtract, so that only those are counted.
+// If that is not possible (e.g. the totals were not found, or checkpoints were
+// rolled back), the whole window is passed to recount instead.
+func updateRunningTotals(found bool, start int, end int, newStart int, newEnd int, add func(int, int) error, subtract func(int, int) error, recount func(int, int) error) error {
+	if !found || newStart < start || newEnd < end {
+		return recount(newStart, newEnd)
+	}
+
+	// add the checkpoints which entered the window
+	if newEnd > end {
+		err := add(end+1, newEnd)
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove the checkpoints which left the window
+	if newStart > start {
+		err := subtract(start, newStart-1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getWindowCounters returns the counters of the passed window, ending at the
+// passed checkpoint. The counters are updated incrementally with
+// updateRunningTotals, and saved in the database.
+func getWindowCounters(window utils.PerformanceWindow, lastCheckpoint int) (utils.WindowCounters, error) {
+	startCheckpoint, err := database.GetWindowStart(window, lastCheckpoint)
+	if err != nil {
+		return utils.WindowCounters{}, err
+	}
+
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+
+	// load the counters from the database if we do not have them in memory
+	counters, ok := windowCounters[window.Name]
+	if !ok {
+		counters, ok, err = database.GetWindowCounters(window.Name)
+		if err != nil {
+			return utils.WindowCounters{}, err
+		}
+	}
+
+	if ok && startCheckpoint == counters.Start && lastCheckpoint == counters.End {
+		windowCounters[window.Name] = counters
+		return counters, nil
+	}
+
+	// work on a copy, so that the counters are left as they are on error
+	updated := utils.WindowCounters{Window: window.Name, Start: startCheckpoint, End: lastCheckpoint, Checkpoints: counters.Checkpoints, Signed: map[int]int{}}
+	for validatorId, signed := range counters.Signed {
+		updated.Signed[validatorId] = signed
+	}
+
+	addSigned := func(sign int) func(int, int) error {
+		return func(start int, end int) error {
+			checkpointCount, signedCounts, err := database.GetSignedCountsInRange(start, end)
+			if err != nil {
+				return err
+			}
+			updated.Checkpoints += sign * checkpointCount
+			for validatorId, signed := range signedCounts {
+				updated.Signed[validatorId] += sign * signed
+				if updated.Signed[validatorId] <= 0 {
+					delete(updated.Signed, validatorId)
+				}
+			}
+			return nil
+		}
+	}
+	recount := func(start int, end int) error {
+		checkpointCount, signedCounts, err := database.GetSignedCountsInRange(start, end)
+		if err != nil {
+			return err
+		}
+		updated.Checkpoints, updated.Signed = checkpointCount, signedCounts
+		return nil
+	}
+
+	err = updateRunningTotals(ok, counters.Start, counters.End, startCheckpoint, lastCheckpoint, addSigned(1), addSigned(-1), recount)
+	if err != nil {
+		return utils.WindowCounters{}, err
+	}
+
+	err = database.SaveWindowCounters(updated)
+	if err != nil {
+		return utils.WindowCounters{}, err
+	}
+	windowCounters[window.Name] = updated
+
+	return updated, nil
+}
+
+// recountWindow counts the checkpoints within the passed range, and the number
+// of those signed by each validator, from scratch. The result replaces the
+// counters of the passed window, both in memory and in the database. The
+// caller must hold countersMutex.
+func recountWindow(window string, startCheckpoint int, lastCheckpoint int) (utils.WindowCounters, error) {
+	checkpointCount, signedCounts, err := database.GetSignedCountsInRange(startCheckpoint, lastCheckpoint)
+	if err != nil {
+		return utils.WindowCounters{}, err
+	}
+
+	counters := utils.WindowCounters{Window: window, Start: startCheckpoint, End: lastCheckpoint, Checkpoints: checkpointCount, Signed: signedCounts}
+
+	err = database.SaveWindowCounters(counters)
+	if err != nil {
+		return utils.WindowCounters{}, err
+	}
+	windowCounters[window] = counters
+
+	return counters, nil
+}
+
+// VerifyCounters compares the running counters of each performance window with
+// a full recount of the same range, for every validator. Every mismatch is
+// logged. Counters which end after the last checkpoint stored, as checkpoints
+// were rolled back since, count as one mismatch and are recounted up to it.
+// If repair is true, mismatched counters are replaced with the recount, and
+// the running totals only kept in memory are dropped to be counted in full
+// again. It returns the number of mismatches found.
+func VerifyCounters(repair bool) (int, error) {
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+
+	lastCheckpoint, err := database.GetLastCheckpointNumber()
+	if err == sql.ErrNoRows {
+		lastCheckpoint = 0
+	} else if err != nil {
+		return 0, err
+	}
+
+	mismatches := 0
+	for _, window := range countedWindows() {
+		counters, ok := windowCounters[window.Name]
+		if !ok {
+			counters, ok, err = database.GetWindowCounters(window.Name)
+			if err != nil {
+				return mismatches, err
+			}
+			if !ok {
+				// nothing counted yet for this window
+				continue
+			}
+		}
+
+		// the counters include checkpoints which were rolled back, so they
+		// would not be counted again once processed again
+		if counters.End > lastCheckpoint {
+			slog.Warn("Counters include checkpoints which were rolled back", "window", window.Name, "end", counters.End, "last_checkpoint", lastCheckpoint)
+			mismatches++
+
+			if repair {
+				startCheckpoint := 0
+				if lastCheckpoint > 0 {
+					startCheckpoint, err = database.GetWindowStart(window, lastCheckpoint)
+					if err != nil {
+						return mismatches, err
+					}
+				}
+				_, err = recountWindow(window.Name, startCheckpoint, lastCheckpoint)
+				if err != nil {
+					return mismatches, err
+				}
+			}
+			continue
+		}
+
+		checkpointCount, signedCounts, err := database.GetSignedCountsInRange(counters.Start, counters.End)
+		if err != nil {
+			return mismatches, err
+		}
+
+		windowMismatches := 0
+		if checkpointCount != counters.Checkpoints {
+			slog.Warn("Checkpoint counter does not match recount", "window", window.Name, "counter", counters.Checkpoints, "recount", checkpointCount)
+			windowMismatches++
+		}
+		for validatorId, signed := range signedCounts {
+			if signed != counters.Signed[validatorId] {
+				slog.Warn("Signed checkpoints counter does not match recount", "window", window.Name, "validator_id", validatorId, "counter", counters.Signed[validatorId], "recount", signed)
+				windowMismatches++
+			}
+		}
+		for validatorId, signed := range counters.Signed {
+			if _, ok := signedCounts[validatorId]; !ok && signed != 0 {
+				slog.Warn("Signed checkpoints counter does not match recount", "window", window.Name, "validator_id", validatorId, "counter", signed, "recount", 0)
+				windowMismatches++
+			}
+		}
+		mismatches += windowMismatches
+
+		if windowMismatches > 0 && repair {
+			_, err = recountWindow(window.Name, counters.Start, counters.End)
+			if err != nil {
+				return mismatches, err
+			}
+		}
+	}
+
+	if repair {
+		ResetRunningTotals()
+	}
+	countersLastVerified = time.Now()
+
+	return mismatches, nil
+}
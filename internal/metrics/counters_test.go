@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// useCountersDatabase creates a new database for the rest of the test, holding
+// checkpoints 1 to 20, 10 seconds apart. Validator 1 signed all of them,
+// validator 2 the even ones and validator 3 every third one. The running
+// counters kept in memory are dropped, so that they are loaded from the new
+// database.
+func useCountersDatabase(t *testing.T, windows []string) {
+	previous := utils.GetConfig()
+	utils.SetConfig(utils.GeneralSettings{DatabaseLocation: filepath.Join(t.TempDir(), "test.db"), PerformanceWindows: windows})
+	t.Cleanup(func() { utils.SetConfig(*previous) })
+
+	countersMutex.Lock()
+	windowCounters = map[string]utils.WindowCounters{}
+	countersMutex.Unlock()
+
+	err := database.CreateDatabase()
+	if err != nil {
+		t.Fatalf("CreateDatabase() returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO validators(id, owner_key, signer_key, activation_epoch, deactivation_epoch) VALUES
+			(1, 'owner1', 'signer1', 1, 0),
+			(2, 'owner2', 'signer2', 1, 0),
+			(3, 'owner3', 'signer3', 1, 0)`)
+	if err != nil {
+		t.Fatalf("could not insert validators: %v", err)
+	}
+
+	for number := 1; number <= 20; number++ {
+		_, err = db.Exec(`INSERT INTO checkpoints(id, number, block_number, timestamp, proposer_id, reward) VALUES (?, ?, ?, ?, 1, 0)`,
+			number, number, 1000+number, 1700000000+10*number)
+		if err != nil {
+			t.Fatalf("could not insert checkpoint %d: %v", number, err)
+		}
+
+		signers := []int{1}
+		if number%2 == 0 {
+			signers = append(signers, 2)
+		}
+		if number%3 == 0 {
+			signers = append(signers, 3)
+		}
+		for _, validatorId := range signers {
+			_, err = db.Exec(`INSERT INTO validators_signed_checkpoints(checkpoint_id, validator_id) VALUES (?, ?)`, number, validatorId)
+			if err != nil {
+				t.Fatalf("could not insert signer of checkpoint %d: %v", number, err)
+			}
+		}
+	}
+}
+
+func TestCountedWindows(t *testing.T) {
+	previous := utils.GetConfig()
+	t.Cleanup(func() { utils.SetConfig(*previous) })
+
+	tests := []struct {
+		name     string
+		windows  []string
+		expected []string
+	}{
+		{"default", nil, []string{"700", "total"}},
+		{"duration only", []string{"24h"}, []string{"1d", "700", "total"}},
+		{"already counted", []string{"total", "7d", "700"}, []string{"total", "7d", "700"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			utils.SetConfig(utils.GeneralSettings{PerformanceWindows: test.windows})
+
+			if names := windowNames(countedWindows()); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("countedWindows() = %v, expected %v", names, test.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateRunningTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		found    bool
+		start    int
+		end      int
+		newStart int
+		newEnd   int
+		expected []string
+	}{
+		{"not found", false, 0, 0, 11, 20, []string{"recount 11-20"}},
+		{"unchanged", true, 11, 20, 11, 20, []string{}},
+		{"advanced", true, 11, 20, 12, 21, []string{"add 21-21", "subtract 11-11"}},
+		{"advanced past the window", true, 11, 20, 31, 40, []string{"add 21-40", "subtract 11-30"}},
+		{"grown", true, 0, 20, 0, 25, []string{"add 21-25"}},
+		{"shrunk", true, 11, 20, 15, 20, []string{"subtract 11-14"}},
+		{"rolled back", true, 11, 20, 10, 19, []string{"recount 10-19"}},
+		{"start moved back", true, 11, 20, 10, 21, []string{"recount 10-21"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := []string{}
+			record := func(name string) func(int, int) error {
+				return func(start int, end int) error {
+					calls = append(calls, fmt.Sprintf("%s %d-%d", name, start, end))
+					return nil
+				}
+			}
+
+			err := updateRunningTotals(test.found, test.start, test.end, test.newStart, test.newEnd, record("add"), record("subtract"), record("recount"))
+			if err != nil {
+				t.Fatalf("updateRunningTotals() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(calls, test.expected) {
+				t.Errorf("updateRunningTotals() made calls %v, expected %v", calls, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetWindowCounters(t *testing.T) {
+	tests := []struct {
+		name   string
+		window string
+		ends   []int
+	}{
+		{"checkpoints advancing", "5", []int{5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}},
+		{"checkpoints jumping", "5", []int{5, 12, 13, 20}},
+		{"checkpoints rolled back", "5", []int{20, 15, 17}},
+		{"checkpoints before the first", "5", []int{2, 3, 9}},
+		{"duration advancing", "45s", []int{5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}},
+		{"duration rolled back", "45s", []int{20, 12, 14}},
+		{"total", "total", []int{5, 6, 10, 20, 18}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useCountersDatabase(t, []string{test.window})
+			window, err := utils.ParsePerformanceWindow(test.window)
+			if err != nil {
+				t.Fatalf("ParsePerformanceWindow() returned error: %v", err)
+			}
+
+			for _, end := range test.ends {
+				counters, err := getWindowCounters(window, end)
+				if err != nil {
+					t.Fatalf("getWindowCounters(%d) returned error: %v", end, err)
+				}
+
+				start, err := database.GetWindowStart(window, end)
+				if err != nil {
+					t.Fatalf("GetWindowStart(%d) returned error: %v", end, err)
+				}
+				checkpointCount, signedCounts, err := database.GetSignedCountsInRange(start, end)
+				if err != nil {
+					t.Fatalf("GetSignedCountsInRange(%d, %d) returned error: %v", start, end, err)
+				}
+
+				expected := utils.WindowCounters{Window: window.Name, Start: start, End: end, Checkpoints: checkpointCount, Signed: signedCounts}
+				if !reflect.DeepEqual(counters, expected) {
+					t.Errorf("getWindowCounters(%d) = %+v, full recount %+v", end, counters, expected)
+				}
+
+				saved, found, err := database.GetWindowCounters(window.Name)
+				if err != nil || !found || !reflect.DeepEqual(saved, expected) {
+					t.Errorf("GetWindowCounters() = %+v, %v, %v, expected %+v", saved, found, err, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyCounters(t *testing.T) {
+	tests := []struct {
+		name             string
+		deleteSQL        string
+		windowMismatches int
+	}{
+		{"matching", "", 0},
+		// validator 3 signed checkpoint 18, which is within every window
+		{"signer removed", `DELETE FROM validators_signed_checkpoints WHERE checkpoint_id = 18 AND validator_id = 3`, 1},
+		// the checkpoint count, and the counters of every validator, as they
+		// all signed checkpoint 18
+		{"checkpoint removed", `DELETE FROM checkpoints WHERE number = 18`, 4},
+		// the counters end after the last checkpoint
+		{"last checkpoint rolled back", `DELETE FROM checkpoints WHERE number = 20`, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useCountersDatabase(t, []string{"5"})
+
+			windows := countedWindows()
+			for _, window := range windows {
+				_, err := getWindowCounters(window, 20)
+				if err != nil {
+					t.Fatalf("getWindowCounters() returned error: %v", err)
+				}
+			}
+
+			if test.deleteSQL != "" {
+				db, err := sql.Open("sqlite3", utils.GetConfig().DatabaseLocation)
+				if err != nil {
+					t.Fatalf("could not open database: %v", err)
+				}
+				_, err = db.Exec(test.deleteSQL)
+				db.Close()
+				if err != nil {
+					t.Fatalf("could not change database: %v", err)
+				}
+			}
+
+			expected := test.windowMismatches * len(windows)
+			mismatches, err := VerifyCounters(false)
+			if err != nil || mismatches != expected {
+				t.Errorf("VerifyCounters(false) = %d, %v, expected %d mismatches", mismatches, err, expected)
+			}
+
+			// the mismatches are only repaired when asked to
+			mismatches, err = VerifyCounters(true)
+			if err != nil || mismatches != expected {
+				t.Errorf("VerifyCounters(true) = %d, %v, expected %d mismatches", mismatches, err, expected)
+			}
+			mismatches, err = VerifyCounters(false)
+			if err != nil || mismatches != 0 {
+				t.Errorf("VerifyCounters(false) after repair = %d, %v, expected no mismatches", mismatches, err)
+			}
+		})
+	}
+}
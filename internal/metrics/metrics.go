@@ -2,8 +2,12 @@ package metrics
 
 import (
 	"database/sql"
-	"fmt"
+	"log/slog"
 	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	database "monitor/internal/db"
 	"monitor/internal/utils"
@@ -17,7 +21,7 @@ var (
 	checkpointsSigned = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "checkpoints_signed",
 		Help: "The number of checkpoints signed by a validator for the given range",
-	}, []string{"validator", "range"})
+	}, []string{"validator_id", "validator", "name", "range"})
 
 	checkpointsTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "checkpoints_total",
@@ -27,7 +31,7 @@ var (
 	validatorPerformance = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "validator_performance",
 		Help: "The percentage of checkpoints signed for the given range",
-	}, []string{"validator", "range"})
+	}, []string{"validator_id", "validator", "name", "range"})
 
 	CurrentCheckpoint = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "current_checkpoint",
@@ -36,7 +40,7 @@ var (
 
 	CurrentBlockNumber = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "current_block_number",
-		Help: "The latest ETH block number processed by the monitor",
+		Help: "The next ETH block number to be processed by the monitor",
 	})
 
 	CurrentPerformanceBenchmark = promauto.NewGauge(prometheus.GaugeOpts{
@@ -44,24 +48,497 @@ var (
 		Help: "The performance benchmark as of the last checkpoint processed by the monitor.",
 	})
 
+	performanceDistribution = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "performance_distribution",
+		Help: "The performance of the active validator set over the last 700 checkpoints, at the given quantile (0 is the minimum and 1 the maximum).",
+	}, []string{"quantile"})
+
+	validatorsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "validators_active",
+		Help: "The number of validators counted in the performance benchmark as of the last checkpoint processed.",
+	})
+
+	validatorsBelowPB = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "validators_below_performance_benchmark",
+		Help: "The number of active validators whose performance over the last 700 checkpoints is below the performance benchmark.",
+	})
+
+	validatorsBelowFullPerformance = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "validators_below_full_performance",
+		Help: "The number of active validators which did not sign all of the last 700 checkpoints.",
+	})
+
+	MetricsStale = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "metrics_stale",
+		Help: "Set to 1 while the monitor is failing to process new checkpoints, or some metrics could not be read from the database, meaning the other metrics may be out of date.",
+	})
+
 	checkpointsToPB = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "checkpoints_to_performance_benchmark",
 		Help: "How many checkpoints the associated validator must miss to fall below the performance benchmark.",
-	}, []string{"validator"})
+	}, []string{"validator_id", "validator", "name"})
 
 	checkpointsToReduction = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "checkpoints_to_reduction",
 		Help: "How many checkpoints the associated validator has to go through until it gets the first improvement in PB.",
-	}, []string{"validator"})
+	}, []string{"validator_id", "validator", "name"})
+
+	secondsToPB = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "seconds_to_performance_benchmark",
+		Help: "Estimated number of seconds until the associated validator falls below the performance benchmark, if it misses every checkpoint, based on the average checkpoint interval.",
+	}, []string{"validator_id", "validator", "name"})
+
+	secondsToReduction = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "seconds_to_reduction",
+		Help: "Estimated number of seconds until the associated validator gets the first improvement in PB, based on the average checkpoint interval.",
+	}, []string{"validator_id", "validator", "name"})
+
+	averageCheckpointInterval = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "average_checkpoint_interval_seconds",
+		Help: "The average number of seconds between checkpoints, over the last checkpoints processed.",
+	})
+
+	nextCheckpointExpected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "next_checkpoint_expected_timestamp",
+		Help: "The estimated unix timestamp of the next checkpoint, based on the average checkpoint interval.",
+	})
+
+	checkpointInterval = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "checkpoint_interval_seconds",
+		Help:    "The number of seconds between each checkpoint processed and the previous one.",
+		Buckets: []float64{300, 600, 900, 1200, 1500, 1800, 2400, 3600, 5400, 7200, 10800, 21600},
+	})
+
+	lastCheckpointTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "last_checkpoint_timestamp",
+		Help: "The unix timestamp of the block the last checkpoint was included in.",
+	})
+
+	timeSinceLastCheckpoint = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "time_since_last_checkpoint",
+		Help: "The number of seconds since the block the last checkpoint was included in.",
+	})
+
+	stallThreshold = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_stall_threshold_seconds",
+		Help: "The number of seconds without a new checkpoint after which checkpointing is considered stalled.",
+	})
+
+	checkpointStalled = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_stalled",
+		Help: "1 if no checkpoint has landed for longer than the stall threshold, 0 otherwise.",
+	})
+
+	validatorRank = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "validator_rank",
+		Help: "The rank of the validator by performance within the active set for the given range, 1 being the best.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	validatorPercentile = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "validator_percentile",
+		Help: "The percentage of the active set with a lower performance than the validator for the given range.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	validatorDistanceFromMedian = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "validator_distance_from_median",
+		Help: "The performance of the validator minus the median performance of the active set for the given range.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	validatorInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "validator_info",
+		Help: "Always 1. Holds the metadata of each tracked validator, to be joined with the other metrics on validator_id.",
+	}, []string{"validator_id", "validator", "name", "team", "groups"})
+
+	groupValidators = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "group_validators",
+		Help: "The number of validators in the group which are part of the performance benchmark.",
+	}, []string{"group"})
+
+	groupPerformance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "group_performance",
+		Help: "The average performance of the validators in the group, over the last 700 checkpoints.",
+	}, []string{"group"})
+
+	groupMinPerformance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "group_min_performance",
+		Help: "The lowest performance of the validators in the group, over the last 700 checkpoints.",
+	}, []string{"group"})
+
+	groupValidatorsBelowPB = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "group_validators_below_performance_benchmark",
+		Help: "The number of validators in the group whose performance is below the performance benchmark.",
+	}, []string{"group"})
+
+	checkpointsProposed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "checkpoints_proposed",
+		Help: "The number of checkpoints proposed by the validator for the given range.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	proposerReward = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proposer_reward",
+		Help: "The sum of the rewards of the checkpoints proposed by the validator for the given range, in POL.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	proposerRewardUnknown = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proposer_reward_unknown_checkpoints",
+		Help: "The number of checkpoints proposed by the validator for the given range whose reward is not included in proposer_reward, as it was not stored in full when they were processed.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	proposalShare = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proposal_share",
+		Help: "The share of the checkpoints for the given range which were proposed by the validator.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	stakeShare = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stake_share",
+		Help: "The share of the stake of the active set held by the validator, including delegations. This is the expected proposal share.",
+	}, []string{"validator_id", "validator", "name"})
+
+	proposalToStakeShareRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proposal_to_stake_share_ratio",
+		Help: "The proposal share of the validator for the given range divided by its current stake share. Below 1 means it proposed fewer checkpoints than its stake would suggest.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	estimatedReward = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estimated_reward",
+		Help: "The estimated checkpoint rewards earned through the validator's stake for the given range, in POL, including the part going to its delegators.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	estimatedDelegatorReward = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estimated_delegator_reward",
+		Help: "The part of the estimated rewards of the validator for the given range going to its delegators after commission, in POL.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	estimatedRewardPerDelegatedPOL = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estimated_reward_per_delegated_pol",
+		Help: "The estimated rewards earned by each POL delegated to the validator for the given range, after commission, in POL.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	estimatedMissedReward = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estimated_missed_reward",
+		Help: "The estimated rewards the validator would have earned on the checkpoints it did not sign for the given range, in POL.",
+	}, []string{"validator_id", "validator", "name", "range"})
+
+	checkpointGasUsed = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_gas_used",
+		Help: "The gas used by the transaction which submitted the last checkpoint.",
+	})
+
+	checkpointEffectiveGasPrice = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_effective_gas_price_gwei",
+		Help: "The effective gas price paid by the transaction which submitted the last checkpoint, in gwei.",
+	})
+
+	checkpointCost = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_cost_eth",
+		Help: "The cost of the transaction which submitted the last checkpoint, in ETH.",
+	})
+
+	averageCheckpointCost = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "average_checkpoint_cost_eth",
+		Help: "The average cost of submitting a checkpoint, over the last 100 checkpoints, in ETH.",
+	})
+
+	checkpointBorBlocks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_bor_blocks",
+		Help: "The number of Bor blocks covered by the last checkpoint.",
+	})
+
+	checkpointBorEndBlock = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_bor_end_block",
+		Help: "The last Bor block covered by the last checkpoint.",
+	})
+
+	checkpointsVerified = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoints_verified",
+		Help: "The number of checkpoints whose root hash was recomputed from the Bor blocks they cover.",
+	})
+
+	checkpointRootHashMismatches = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_root_hash_mismatches",
+		Help: "The number of verified checkpoints whose root hash did not match the Bor blocks they cover.",
+	})
+
+	checkpointRootHashMatch = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "checkpoint_root_hash_match",
+		Help: "1 if the root hash of the last verified checkpoint matched the Bor blocks it covers, 0 otherwise.",
+	})
+
+	signerBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "signer_eth_balance",
+		Help: "The ETH balance of the validator's signer, which pays for the checkpoints it submits.",
+	}, []string{"validator_id", "validator", "name"})
+
+	signerBalanceLow = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "signer_eth_balance_low",
+		Help: "1 if the ETH balance of the validator's signer is below the configured threshold, 0 otherwise.",
+	}, []string{"validator_id", "validator", "name"})
+
+	signerSubmissionsRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "signer_submissions_remaining",
+		Help: "The number of checkpoints the validator's signer can submit with its ETH balance, at the average cost of the last 100 checkpoints.",
+	}, []string{"validator_id", "validator", "name"})
+
+	failedSubmissions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "failed_submissions",
+		Help: "The number of checkpoint submissions sent by the validator's signer which reverted.",
+	}, []string{"validator_id", "validator", "name"})
+
+	failedSubmissionsCost = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "failed_submissions_cost_eth",
+		Help: "The ETH burned by the checkpoint submissions sent by the validator's signer which reverted.",
+	}, []string{"validator_id", "validator", "name"})
+
+	lastFailedSubmission = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "last_failed_submission_timestamp",
+		Help: "The timestamp of the last checkpoint submission sent by the validator's signer which reverted.",
+	}, []string{"validator_id", "validator", "name"})
+
+	failedSubmissionsSet = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "failed_submissions_set",
+		Help: "The number of checkpoint submissions sent by any signer which reverted.",
+	})
 )
 
+// validatorLabelValues holds the labels last used for each validator's
+// metrics, so that stale series can be removed when a validator rotates its
+// signer key or its metadata changes.
+var validatorLabelValues = map[int][]string{}
+
+// ResetValidatorMetrics removes all the series of the metrics labelled by
+// validator or window, so that validators and windows which are no longer
+// configured stop being exported. They are repopulated on the next call to
+// UpdateCheckpointsSignedMetrics.
+func ResetValidatorMetrics() {
+	checkpointsTotal.Reset()
+	checkpointsSigned.Reset()
+	validatorPerformance.Reset()
+	checkpointsToPB.Reset()
+	checkpointsToReduction.Reset()
+	secondsToPB.Reset()
+	secondsToReduction.Reset()
+	validatorRank.Reset()
+	validatorPercentile.Reset()
+	validatorDistanceFromMedian.Reset()
+	validatorInfo.Reset()
+	groupValidators.Reset()
+	groupPerformance.Reset()
+	groupMinPerformance.Reset()
+	groupValidatorsBelowPB.Reset()
+	checkpointsProposed.Reset()
+	proposerReward.Reset()
+	proposerRewardUnknown.Reset()
+	proposalShare.Reset()
+	stakeShare.Reset()
+	proposalToStakeShareRatio.Reset()
+	estimatedReward.Reset()
+	estimatedDelegatorReward.Reset()
+	estimatedRewardPerDelegatedPOL.Reset()
+	estimatedMissedReward.Reset()
+	signerBalance.Reset()
+	signerBalanceLow.Reset()
+	signerSubmissionsRemaining.Reset()
+	failedSubmissions.Reset()
+	failedSubmissionsCost.Reset()
+	lastFailedSubmission.Reset()
+	validatorLabelValues = map[int][]string{}
+
+	// poll the balances again on the next update, rather than waiting
+	balancesLastUpdated = time.Time{}
+}
+
+// distributionQuantiles are the quantiles exported in the
+// performance_distribution metric.
+var distributionQuantiles = []float64{0, 0.1, 0.25, 0.5, 0.75, 1}
+
+// UpdateDistributionMetrics updates the metrics relating to the distribution
+// of the performance of the active validator set, over the last 700
+// checkpoints. The performance of each active validator and the performance
+// benchmark are to be passed to the function.
+func UpdateDistributionMetrics(performance []float64, pb float64) {
+	for _, quantile := range distributionQuantiles {
+		performanceDistribution.WithLabelValues(strconv.FormatFloat(quantile, 'f', -1, 64)).Set(utils.Percentile(performance, quantile))
+	}
+
+	belowPB, belowFull := 0, 0
+	for _, value := range performance {
+		if value < pb {
+			belowPB++
+		}
+		if value < 1 {
+			belowFull++
+		}
+	}
+
+	validatorsActive.Set(float64(len(performance)))
+	validatorsBelowPB.Set(float64(belowPB))
+	validatorsBelowFullPerformance.Set(float64(belowFull))
+}
+
+// validatorLabels returns the validator_id, validator (current signer key) and
+// name label values for the passed validator, and updates its validator_info
+// metric. If any of the labels changed since the last update, the series
+// labelled with the old values are removed.
+func validatorLabels(validatorId int) (string, string, string) {
+	id := strconv.Itoa(validatorId)
+
+	signerKey := ""
+	metadata := utils.TrackedValidator{}
+	validator, err := database.GetValidator(validatorId)
+	if err == nil {
+		signerKey = validator.SignerAddress.String()
+		metadata = utils.ValidatorMetadata(validator)
+	}
+
+	labels := []string{id, signerKey, metadata.Name, metadata.Team, strings.Join(metadata.Groups, ",")}
+	if previous, ok := validatorLabelValues[validatorId]; ok && strings.Join(previous, "|") != strings.Join(labels, "|") {
+		match := prometheus.Labels{"validator_id": id}
+		checkpointsSigned.DeletePartialMatch(match)
+		validatorPerformance.DeletePartialMatch(match)
+		checkpointsToPB.DeletePartialMatch(match)
+		checkpointsToReduction.DeletePartialMatch(match)
+		secondsToPB.DeletePartialMatch(match)
+		secondsToReduction.DeletePartialMatch(match)
+		validatorRank.DeletePartialMatch(match)
+		validatorPercentile.DeletePartialMatch(match)
+		validatorDistanceFromMedian.DeletePartialMatch(match)
+		checkpointsProposed.DeletePartialMatch(match)
+		proposerReward.DeletePartialMatch(match)
+		proposerRewardUnknown.DeletePartialMatch(match)
+		proposalShare.DeletePartialMatch(match)
+		stakeShare.DeletePartialMatch(match)
+		proposalToStakeShareRatio.DeletePartialMatch(match)
+		estimatedReward.DeletePartialMatch(match)
+		estimatedDelegatorReward.DeletePartialMatch(match)
+		estimatedRewardPerDelegatedPOL.DeletePartialMatch(match)
+		estimatedMissedReward.DeletePartialMatch(match)
+		signerBalance.DeletePartialMatch(match)
+		signerBalanceLow.DeletePartialMatch(match)
+		signerSubmissionsRemaining.DeletePartialMatch(match)
+		failedSubmissions.DeletePartialMatch(match)
+		failedSubmissionsCost.DeletePartialMatch(match)
+		lastFailedSubmission.DeletePartialMatch(match)
+		validatorInfo.DeletePartialMatch(match)
+	}
+	validatorLabelValues[validatorId] = labels
+
+	validatorInfo.WithLabelValues(labels...).Set(1)
+
+	return id, signerKey, metadata.Name
+}
+
+// updateRankMetrics updates the rank, percentile and distance from the median
+// of the tracked validators within the active set, over the last PB_WINDOW
+// checkpoints, all the checkpoints processed and each of the configured
+// performance windows. The passed counters of each window are keyed by window
+// name. Windows other than the last PB_WINDOW checkpoints are only ranked when
+// tracking all validators, as otherwise the performance of the rest of the set
+// is not recorded.
+func updateRankMetrics(setPerformance []utils.ValidatorPerformance, countersByWindow map[string]utils.WindowCounters, trackedIds []int) {
+	validatorRank.Reset()
+	validatorPercentile.Reset()
+	validatorDistanceFromMedian.Reset()
+
+	ranges := map[string]map[int]float64{}
+	for _, window := range countedWindows() {
+		counters := countersByWindow[window.Name]
+		if window.Checkpoints != utils.PB_WINDOW && !utils.CheckIfTrackAll() {
+			continue
+		}
+
+		performance := map[int]float64{}
+		for _, validatorPerformance := range setPerformance {
+			if !validatorPerformance.Active {
+				continue
+			}
+
+			validatorId := validatorPerformance.Validator.ValidatorId
+			if window.Checkpoints == utils.PB_WINDOW {
+				performance[validatorId] = validatorPerformance.Performance
+			} else if counters.Checkpoints > 0 {
+				performance[validatorId] = float64(counters.Signed[validatorId]) / float64(counters.Checkpoints)
+			}
+		}
+		ranges[window.Name] = performance
+	}
+
+	for rangeName, performance := range ranges {
+		values := []float64{}
+		for _, value := range performance {
+			values = append(values, value)
+		}
+		median := utils.Median(values)
+
+		for _, rank := range utils.RankPerformance(performance) {
+			if !utils.Contains(trackedIds, rank.ValidatorId) {
+				continue
+			}
+
+			id, signerKey, name := validatorLabels(rank.ValidatorId)
+			validatorRank.WithLabelValues(id, signerKey, name, rangeName).Set(float64(rank.Rank))
+			validatorPercentile.WithLabelValues(id, signerKey, name, rangeName).Set(rank.Percentile)
+			validatorDistanceFromMedian.WithLabelValues(id, signerKey, name, rangeName).Set(rank.Performance - median)
+		}
+	}
+}
+
+// updateGroupMetrics updates the aggregate metrics of each configured group of
+// validators, along with the rest of the set (the "other" group). All the
+// validators counted in the performance benchmark are considered, whether they
+// are tracked or not. Nothing is exported if no groups are configured.
+func updateGroupMetrics(setPerformance []utils.ValidatorPerformance, pb float64, pbFound bool) {
+	groupValidators.Reset()
+	groupPerformance.Reset()
+	groupMinPerformance.Reset()
+	groupValidatorsBelowPB.Reset()
+
+	if !utils.GroupsConfigured() {
+		return
+	}
+
+	// group the performance of each validator in the set
+	groupsPerformance := map[string][]float64{}
+	for _, validatorPerformance := range setPerformance {
+		if !validatorPerformance.Active {
+			continue
+		}
+
+		groups := utils.ValidatorMetadata(validatorPerformance.Validator).Groups
+		if len(groups) == 0 {
+			groups = []string{utils.OTHER_GROUP}
+		}
+
+		for _, group := range groups {
+			groupsPerformance[group] = append(groupsPerformance[group], validatorPerformance.Performance)
+		}
+	}
+
+	for group, performance := range groupsPerformance {
+		sum, min, belowPB := 0.0, math.Inf(1), 0
+		for _, value := range performance {
+			sum += value
+			min = math.Min(min, value)
+			if pbFound && value < pb {
+				belowPB++
+			}
+		}
+
+		groupValidators.WithLabelValues(group).Set(float64(len(performance)))
+		groupPerformance.WithLabelValues(group).Set(sum / float64(len(performance)))
+		groupMinPerformance.WithLabelValues(group).Set(min)
+		if pbFound {
+			groupValidatorsBelowPB.WithLabelValues(group).Set(float64(belowPB))
+		}
+	}
+}
+
 // calculateCheckpointsToPB calculates and returns how many more checkpoints the
 // validator has to miss to fall below the *current* performance benchmark. The
 // performance benchmark and the number of checkpoints signed by the validator
 // (assuming of the last 700) are to be passed to the function.
 func calculateCheckpointsToPB(pb float64, checkpointsSigned int) int {
 	// convert the pb to percentage signed, assuming a 700 checkpoint range
-	pbSigned := int(math.Floor(pb * float64(700))) // this is the maximum we can sign and still be below the pb
+	pbSigned := int(math.Floor(pb * float64(utils.PB_WINDOW))) // this is the maximum we can sign and still be below the pb
 
 	// if validator has already reached or is below pb
 	if checkpointsSigned <= pbSigned {
@@ -76,10 +553,10 @@ func calculateCheckpointsToPB(pb float64, checkpointsSigned int) int {
 // has to go through before seeing an improvement in their performance. It
 // essentially gets the first checkpoint missed of the past 700 and calculates
 // how many checkpoints remain from that checkpoint + 700.
-func checkpointsToMissReduce(signerKey string, checkpointNumber int) (int, error) {
+func checkpointsToMissReduce(validatorId int, checkpointNumber int) (int, error) {
 	// get the first checkpoint the validator missed within the 700 checkpoint
 	// range
-	firstMiss, err := database.GetFirstMissedCheckpointRange(signerKey, checkpointNumber-699, checkpointNumber)
+	firstMiss, err := database.GetFirstMissedCheckpointRange(validatorId, checkpointNumber-(utils.PB_WINDOW-1), checkpointNumber)
 	if err != nil {
 		return 0, err
 	}
@@ -88,99 +565,244 @@ func checkpointsToMissReduce(signerKey string, checkpointNumber int) (int, error
 	// miss is no longer considered in the performance benchmark (i.e. the
 	// checkpoint in which the validator missed, will no longer be part of the
 	// past 700, thus not used in the performance benchmark)
-	return firstMiss + 700 - checkpointNumber, nil
+	return firstMiss + utils.PB_WINDOW - checkpointNumber, nil
+}
+
+// readFailed is set when some of the metrics could not be read from the
+// database the last time they were updated, so they keep an old value.
+var readFailed atomic.Bool
+
+// SetMetricsStale sets MetricsStale to the passed value. It is always set if
+// some of the metrics could not be read from the database the last time they
+// were updated.
+func SetMetricsStale(stale bool) {
+	if stale || readFailed.Load() {
+		MetricsStale.Set(1)
+	} else {
+		MetricsStale.Set(0)
+	}
 }
 
 // UpdateCheckpointsSignedMetrics updates metrics related to checkpoints and the
 // performance of validators. It does not take any passed values, instead
-// getting all values from the database.
+// getting all values from the database. Only errors while updating the running
+// counters, which are saved in the database, are returned. Any other metric
+// which cannot be updated is logged and keeps its last value, and MetricsStale
+// is set until all of them are updated again.
 func UpdateCheckpointsSignedMetrics() error {
+	failed := false
+	defer func() {
+		readFailed.Store(failed)
+		if failed {
+			MetricsStale.Set(1)
+		}
+	}()
+
 	// update last checkpoint metric
 	lastCheckpoint, err := database.GetLastCheckpointNumber()
-	if err == nil {
-		CurrentCheckpoint.Set(float64(lastCheckpoint))
+	if err != nil {
+		// if the database is empty (i.e. has no processed any checkpoint yet)
+		// then we cannot set any metrics
+		if err == sql.ErrNoRows {
+			slog.Warn("Database is empty, no metrics to update")
+		} else {
+			slog.Warn("Could not get the last checkpoint, so no metrics are updated", "error", err)
+			failed = true
+		}
+		return nil
+	}
+	CurrentCheckpoint.Set(float64(lastCheckpoint))
 
-		// get the number of checkpoints we have of the last 700, and the
-		// number of these that were signed by the tracked validators
-		checkpointCount700, checkpointPerformance700, err := database.GetCheckpointCount(lastCheckpoint-699, lastCheckpoint)
+	// verify the running counters against a full recount on startup, and then
+	// periodically
+	if time.Since(countersLastVerified) > COUNTERS_VERIFY_INTERVAL {
+		mismatches, err := VerifyCounters(true)
 		if err != nil {
-			return err
+			slog.Warn("Could not verify the running counters against a full recount", "error", err)
+			failed = true
+		} else if mismatches > 0 {
+			slog.Warn("Running counters did not match a full recount and were recounted", "mismatches", mismatches)
 		}
+	}
 
-		// update the total number of checkpoints (of the last 700) metric
-		checkpointsTotal.WithLabelValues("700").Set(float64(checkpointCount700))
-
-		// for every tracked validator, update the metrics relating to the
-		// number of checkpoints they signed, and their performance (of the last
-		// 700)
-		for publicKey, value := range checkpointPerformance700 {
-			checkpointsSigned.WithLabelValues(publicKey, "700").Set(float64(value))
-			validatorPerformance.WithLabelValues(publicKey, "700").Set(float64(value) / float64(checkpointCount700))
-		}
+	// get the ids of the validators we are tracking
+	trackedIds, err := database.ResolveTrackedValidators()
+	if err != nil {
+		slog.Warn("Could not resolve the tracked validators, so their metrics are not updated", "error", err)
+		failed = true
+		trackedIds = []int{}
+	}
 
-		// get the total number of checkpoints we have, and the number of these
-		// that were signed by the tracked validators
-		checkpointCountTotal, checkpointPerformanceTotal, err := database.GetCheckpointCount(0, lastCheckpoint)
+	// for every window, update the total number of checkpoints in the window
+	// and, for every tracked validator, the metrics relating to the number of
+	// checkpoints they signed and their performance. The 700 checkpoint and
+	// total windows are always counted, as they are needed for the performance
+	// benchmark and rank metrics below, but they are only exported if
+	// configured.
+	configuredWindows := utils.PerformanceWindows()
+	checkpointPerformance700 := map[int]int{}
+	countersByWindow := map[string]utils.WindowCounters{}
+	for _, window := range countedWindows() {
+		counters, err := getWindowCounters(window, lastCheckpoint)
 		if err != nil {
 			return err
 		}
 
-		// update the total number of checkpoints metric
-		checkpointsTotal.WithLabelValues("total").Set(float64(checkpointCountTotal))
-
-		// for every tracked validator, update the metrics relating to the
-		// number of checkpoints they signed, and their performance
-		for publicKey, value := range checkpointPerformanceTotal {
-			checkpointsSigned.WithLabelValues(publicKey, "total").Set(float64(value))
-			validatorPerformance.WithLabelValues(publicKey, "total").Set(float64(value) / float64(checkpointCountTotal))
-		}
-
-		// update performance benchmark metrics
-		pb, err := database.GetPBAtCheckpoint(lastCheckpoint)
-		if err == nil {
-			// call fn to calculate checkpoints to pb for tracked validators
-			for publicKey, value := range checkpointPerformance700 {
-				// update the respective metric, for the respective validator
-				checkpointsToPB.WithLabelValues(publicKey).Set(float64(calculateCheckpointsToPB(pb, value)))
-				if value == 700 {
-					// if we signed all the past 700 checkpoints, then this
-					// value should be 0
-					checkpointsToReduction.WithLabelValues(publicKey).Set(float64(0))
-				} else {
-					// otherwise, calculate it
-					checkpointsToReduce, err := checkpointsToMissReduce(publicKey, lastCheckpoint)
-					if err != nil {
-						return err
-					}
-					// and update the metric
-					checkpointsToReduction.WithLabelValues(publicKey).Set(float64(checkpointsToReduce))
-				}
+		checkpointPerformance := map[int]int{}
+		for _, validatorId := range trackedIds {
+			checkpointPerformance[validatorId] = counters.Signed[validatorId]
+		}
+
+		if window.Checkpoints == utils.PB_WINDOW {
+			checkpointPerformance700 = checkpointPerformance
+		}
+		countersByWindow[window.Name] = counters
+		if !utils.ContainsString(windowNames(configuredWindows), window.Name) {
+			continue
+		}
+
+		checkpointsTotal.WithLabelValues(window.Name).Set(float64(counters.Checkpoints))
+
+		for validatorId, value := range checkpointPerformance {
+			id, signerKey, name := validatorLabels(validatorId)
+			checkpointsSigned.WithLabelValues(id, signerKey, name, window.Name).Set(float64(value))
+			if counters.Checkpoints > 0 {
+				validatorPerformance.WithLabelValues(id, signerKey, name, window.Name).Set(float64(value) / float64(counters.Checkpoints))
 			}
+		}
+	}
+
+	// get the performance of the whole set, over the last 700 checkpoints
+	setPerformance, setErr := database.GetSetPerformance700(lastCheckpoint)
+	if setErr != nil {
+		slog.Warn("Could not get the performance of the active set, so the rank and group metrics are not updated", "error", setErr)
+		failed = true
+	} else {
+		// update the rank of the tracked validators within the active set
+		updateRankMetrics(setPerformance, countersByWindow, trackedIds)
+	}
+
+	// update the proposer metrics of the tracked validators
+	err = updateProposerMetrics(lastCheckpoint, trackedIds)
+	if err != nil {
+		slog.Warn("Could not update the proposer metrics", "error", err)
+		failed = true
+	}
+
+	// update the estimated rewards of the tracked validators
+	err = updateRewardMetrics(lastCheckpoint, trackedIds)
+	if err != nil {
+		slog.Warn("Could not update the estimated reward metrics", "error", err)
+		failed = true
+	}
+
+	// update the cost of submitting checkpoints
+	err = updateCostMetrics(lastCheckpoint)
+	if err != nil {
+		slog.Warn("Could not update the checkpoint cost metrics", "error", err)
+		failed = true
+	}
+
+	// update the range of Bor blocks covered by the last checkpoint
+	err = updateCheckpointDataMetrics(lastCheckpoint)
+	if err != nil {
+		slog.Warn("Could not update the checkpoint contents metrics", "error", err)
+		failed = true
+	}
+
+	// update the result of verifying the root hash of the checkpoints
+	err = UpdateVerificationMetrics()
+	if err != nil {
+		slog.Warn("Could not update the checkpoint verification metrics", "error", err)
+		failed = true
+	}
+
+	// update the failed submissions of the tracked validators
+	err = UpdateFailedSubmissionMetrics()
+	if err != nil {
+		slog.Warn("Could not update the failed submission metrics", "error", err)
+		failed = true
+	}
+
+	// update the checkpoint cadence metrics, based on the average interval
+	// between the last checkpoints
+	interval, err := database.GetAverageCheckpointInterval(lastCheckpoint-utils.CADENCE_CHECKPOINTS, lastCheckpoint)
+	if err != nil {
+		slog.Warn("Could not get the average checkpoint interval, so the cadence metrics are not updated", "error", err)
+		failed = true
+		interval = 0
+	}
+	if interval > 0 {
+		lastTimestamp, err := database.GetCheckpointTimestamp(lastCheckpoint)
+		if err != nil {
+			slog.Warn("Could not get the timestamp of the last checkpoint, so the cadence metrics are not updated", "error", err)
+			failed = true
+			interval = 0
 		} else {
-			if err == sql.ErrNoRows {
-				// we do not have the data to calculate the metric
-				return nil
-			} else {
-				switch err.(type) {
-				case *utils.CheckpointNotFoundError:
-					// it is somewhat impossible to get to this point, but in
-					// case we do, do not panic
-					return nil
-				default:
-					return err
-				}
+			averageCheckpointInterval.Set(interval)
+			nextCheckpointExpected.Set(float64(lastTimestamp) + interval)
+		}
+	}
+
+	pb, err := database.GetPBAtCheckpoint(lastCheckpoint)
+
+	// update the aggregate metrics of the configured groups of validators
+	if setErr == nil {
+		updateGroupMetrics(setPerformance, pb, err == nil)
+	}
+
+	if err == sql.ErrNoRows {
+		// we do not have the data to calculate the metrics below
+		return nil
+	} else if err != nil {
+		switch err.(type) {
+		case *utils.CheckpointNotFoundError:
+			// it is somewhat impossible to get to this point, but in case we
+			// do, do not panic
+		default:
+			slog.Warn("Could not get the performance benchmark of the last checkpoint", "checkpoint", lastCheckpoint, "error", err)
+			failed = true
+		}
+		return nil
+	}
+
+	// update performance benchmark metrics. Call fn to calculate checkpoints
+	// to pb for tracked validators
+	for validatorId, value := range checkpointPerformance700 {
+		id, signerKey, name := validatorLabels(validatorId)
+		// update the respective metric, for the respective validator
+		checkpointsToPBValue := calculateCheckpointsToPB(pb, value)
+		checkpointsToPB.WithLabelValues(id, signerKey, name).Set(float64(checkpointsToPBValue))
+
+		// if we signed all the past 700 checkpoints, then this value should be
+		// 0, otherwise calculate it
+		checkpointsToReduce := 0
+		if value != utils.PB_WINDOW {
+			checkpointsToReduce, err = checkpointsToMissReduce(validatorId, lastCheckpoint)
+			if err != nil {
+				slog.Warn("Could not calculate the checkpoints until the performance of validator improves", "validator_id", validatorId, "error", err)
+				failed = true
+				continue
 			}
 		}
+		// and update the metric
+		checkpointsToReduction.WithLabelValues(id, signerKey, name).Set(float64(checkpointsToReduce))
 
-	} else {
-		// if the database is empty (i.e. has no processed any checkpoint yet)
-		// then we cannot set any metrics
-		if err == sql.ErrNoRows {
-			fmt.Println("WARN: Database is empty, no metrics to update.")
-			return nil
+		// estimate the same in seconds, based on the cadence
+		if interval > 0 {
+			secondsToPB.WithLabelValues(id, signerKey, name).Set(float64(checkpointsToPBValue) * interval)
+			secondsToReduction.WithLabelValues(id, signerKey, name).Set(float64(checkpointsToReduce) * interval)
 		}
 	}
 
-	// in case of any other error, return it
-	return err
+	return nil
+}
+
+// windowNames returns the names of the passed performance windows.
+func windowNames(windows []utils.PerformanceWindow) []string {
+	names := []string{}
+	for _, window := range windows {
+		names = append(names, window.Name)
+	}
+	return names
 }
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"math/big"
+
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// proposerTotals holds the running totals of the proposer metrics of a
+// performance window: the range of checkpoints it covers, the number of
+// checkpoints within that range, and the checkpoints proposed by each
+// validator along with their rewards, keyed by validator id.
+type proposerTotals struct {
+	Start       int
+	End         int
+	Checkpoints int
+	Proposers   map[int]utils.ProposerStats
+}
+
+// the running proposer totals of each performance window, keyed by window name
+var proposerWindows = map[string]proposerTotals{}
+
+// getProposerTotals returns the proposer totals of the passed window, ending at
+// the passed checkpoint. The totals are updated incrementally with
+// updateRunningTotals, and only kept in memory.
+func getProposerTotals(window utils.PerformanceWindow, lastCheckpoint int) (proposerTotals, error) {
+	startCheckpoint, err := database.GetWindowStart(window, lastCheckpoint)
+	if err != nil {
+		return proposerTotals{}, err
+	}
+
+	totalsMutex.Lock()
+	defer totalsMutex.Unlock()
+
+	// work on a copy, so that the totals are left as they are on error. The
+	// rewards are never modified in place, so they can be shared.
+	totals, ok := proposerWindows[window.Name]
+	updated := proposerTotals{Start: startCheckpoint, End: lastCheckpoint, Checkpoints: totals.Checkpoints, Proposers: map[int]utils.ProposerStats{}}
+	for validatorId, stats := range totals.Proposers {
+		updated.Proposers[validatorId] = stats
+	}
+
+	addProposers := func(sign int) func(int, int) error {
+		return func(start int, end int) error {
+			checkpointCount, proposers, err := database.GetProposerStatsInRange(start, end)
+			if err != nil {
+				return err
+			}
+			updated.Checkpoints += sign * checkpointCount
+			addProposerStats(updated.Proposers, proposers, sign)
+			return nil
+		}
+	}
+	recount := func(start int, end int) error {
+		checkpointCount, proposers, err := database.GetProposerStatsInRange(start, end)
+		if err != nil {
+			return err
+		}
+		updated.Checkpoints, updated.Proposers = checkpointCount, proposers
+		return nil
+	}
+
+	err = updateRunningTotals(ok, totals.Start, totals.End, startCheckpoint, lastCheckpoint, addProposers(1), addProposers(-1), recount)
+	if err != nil {
+		return proposerTotals{}, err
+	}
+	proposerWindows[window.Name] = updated
+
+	return updated, nil
+}
+
+// addProposerStats adds the passed proposer stats to the totals, or subtracts
+// them if sign is -1. Validators left without any checkpoints are removed.
+func addProposerStats(totals map[int]utils.ProposerStats, proposers map[int]utils.ProposerStats, sign int) {
+	for validatorId, stats := range proposers {
+		total, found := totals[validatorId]
+		if !found {
+			total = utils.ProposerStats{Reward: new(big.Int)}
+		}
+
+		reward := new(big.Int).Set(stats.Reward)
+		if sign < 0 {
+			reward.Neg(reward)
+		}
+		total = utils.ProposerStats{
+			Proposed:      total.Proposed + sign*stats.Proposed,
+			RewardUnknown: total.RewardUnknown + sign*stats.RewardUnknown,
+			Reward:        reward.Add(total.Reward, reward),
+		}
+
+		if total.Proposed <= 0 {
+			delete(totals, validatorId)
+			continue
+		}
+		totals[validatorId] = total
+	}
+}
+
+// updateProposerMetrics updates the number of checkpoints proposed by the
+// tracked validators and the sum of their rewards, over each of the
+// performance windows. The share of the checkpoints proposed is compared with
+// the share of the stake of the active set held by the validator, as proposers
+// are picked in proportion to their stake. The current stake is used for all
+// the windows.
+func updateProposerMetrics(lastCheckpoint int, trackedIds []int) error {
+	stakes, err := database.GetActiveValidatorStakes()
+	if err != nil {
+		return err
+	}
+
+	totalStake := new(big.Int)
+	for _, stake := range stakes {
+		totalStake.Add(totalStake, stake)
+	}
+
+	shares := map[int]float64{}
+	if totalStake.Sign() > 0 {
+		for _, validatorId := range trackedIds {
+			if stake, found := stakes[validatorId]; found {
+				shares[validatorId], _ = new(big.Rat).SetFrac(stake, totalStake).Float64()
+			}
+		}
+	}
+
+	for _, window := range utils.PerformanceWindows() {
+		totals, err := getProposerTotals(window, lastCheckpoint)
+		if err != nil {
+			return err
+		}
+
+		for _, validatorId := range trackedIds {
+			id, signerKey, name := validatorLabels(validatorId)
+
+			stats, found := totals.Proposers[validatorId]
+			if !found {
+				stats = utils.ProposerStats{Reward: new(big.Int)}
+			}
+			checkpointsProposed.WithLabelValues(id, signerKey, name, window.Name).Set(float64(stats.Proposed))
+			proposerReward.WithLabelValues(id, signerKey, name, window.Name).Set(utils.WeiToPOL(stats.Reward))
+			proposerRewardUnknown.WithLabelValues(id, signerKey, name, window.Name).Set(float64(stats.RewardUnknown))
+
+			if totals.Checkpoints == 0 {
+				continue
+			}
+			share := float64(stats.Proposed) / float64(totals.Checkpoints)
+			proposalShare.WithLabelValues(id, signerKey, name, window.Name).Set(share)
+
+			if stakeShareValue, found := shares[validatorId]; found && stakeShareValue > 0 {
+				stakeShare.WithLabelValues(id, signerKey, name).Set(stakeShareValue)
+				proposalToStakeShareRatio.WithLabelValues(id, signerKey, name, window.Name).Set(share / stakeShareValue)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// rewardTotals holds the running totals of the estimated rewards of a
+// performance window: the range of checkpoints it covers, and the sum of the
+// estimated rewards of each validator over that range, keyed by validator id.
+type rewardTotals struct {
+	Start   int
+	End     int
+	Rewards map[int]utils.EstimatedReward
+}
+
+// the running reward totals of each performance window, keyed by window name
+var rewardWindows = map[string]rewardTotals{}
+
+// getRewardTotals returns the estimated reward totals of the passed window,
+// ending at the passed checkpoint. The totals are updated incrementally with
+// updateRunningTotals, and only kept in memory.
+func getRewardTotals(window utils.PerformanceWindow, lastCheckpoint int) (rewardTotals, error) {
+	startCheckpoint, err := database.GetWindowStart(window, lastCheckpoint)
+	if err != nil {
+		return rewardTotals{}, err
+	}
+
+	totalsMutex.Lock()
+	defer totalsMutex.Unlock()
+
+	// work on a copy, so that the totals are left as they are on error
+	totals, ok := rewardWindows[window.Name]
+	updated := rewardTotals{Start: startCheckpoint, End: lastCheckpoint, Rewards: map[int]utils.EstimatedReward{}}
+	for validatorId, reward := range totals.Rewards {
+		updated.Rewards[validatorId] = reward.Copy()
+	}
+
+	add := func(start int, end int) error {
+		rewards, err := database.GetEstimatedRewardsInRange(start, end)
+		if err != nil {
+			return err
+		}
+		for validatorId, reward := range rewards {
+			total, found := updated.Rewards[validatorId]
+			if !found {
+				total = utils.NewEstimatedReward(validatorId)
+				updated.Rewards[validatorId] = total
+			}
+			total.Add(reward)
+		}
+		return nil
+	}
+	subtract := func(start int, end int) error {
+		rewards, err := database.GetEstimatedRewardsInRange(start, end)
+		if err != nil {
+			return err
+		}
+		for validatorId, reward := range rewards {
+			total, found := updated.Rewards[validatorId]
+			if !found {
+				continue
+			}
+			total.Sub(reward)
+			if total.IsZero() {
+				delete(updated.Rewards, validatorId)
+			}
+		}
+		return nil
+	}
+	recount := func(start int, end int) error {
+		rewards, err := database.GetEstimatedRewardsInRange(start, end)
+		if err != nil {
+			return err
+		}
+		updated.Rewards = rewards
+		return nil
+	}
+
+	err = updateRunningTotals(ok, totals.Start, totals.End, startCheckpoint, lastCheckpoint, add, subtract, recount)
+	if err != nil {
+		return rewardTotals{}, err
+	}
+	rewardWindows[window.Name] = updated
+
+	return updated, nil
+}
+
+// updateRewardMetrics updates the estimated rewards of the tracked validators,
+// along with the part going to their delegators and the rewards missed, over
+// each of the performance windows.
+func updateRewardMetrics(lastCheckpoint int, trackedIds []int) error {
+	for _, window := range utils.PerformanceWindows() {
+		totals, err := getRewardTotals(window, lastCheckpoint)
+		if err != nil {
+			return err
+		}
+
+		for _, validatorId := range trackedIds {
+			id, signerKey, name := validatorLabels(validatorId)
+
+			reward, found := totals.Rewards[validatorId]
+			if !found {
+				reward = utils.NewEstimatedReward(validatorId)
+			}
+			estimatedReward.WithLabelValues(id, signerKey, name, window.Name).Set(utils.WeiToPOL(reward.Reward))
+			estimatedDelegatorReward.WithLabelValues(id, signerKey, name, window.Name).Set(utils.WeiToPOL(reward.DelegatorReward))
+			estimatedRewardPerDelegatedPOL.WithLabelValues(id, signerKey, name, window.Name).Set(utils.WeiToPOL(reward.DelegatorRewardPerPOL))
+			estimatedMissedReward.WithLabelValues(id, signerKey, name, window.Name).Set(utils.WeiToPOL(reward.MissedReward))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	database "monitor/internal/db"
+	"monitor/internal/utils"
+)
+
+// UpdateFailedSubmissionMetrics updates the number of failed submissions of
+// every tracked validator, the ETH they burned and when the last one happened,
+// along with the number of failed submissions sent by any signer. It does
+// nothing unless the failed submissions are scanned for, so that a validator
+// is not reported as having none when they are simply not looked for.
+func UpdateFailedSubmissionMetrics() error {
+	if !utils.GetConfig().ScanFailedSubmissions {
+		return nil
+	}
+
+	trackedIds, err := database.ResolveTrackedValidators()
+	if err != nil {
+		return err
+	}
+
+	stats, err := database.GetFailedSubmissionStats()
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, validatorStats := range stats {
+		total += validatorStats.Count
+	}
+	failedSubmissionsSet.Set(float64(total))
+
+	for _, validatorId := range trackedIds {
+		id, signerKey, name := validatorLabels(validatorId)
+
+		validatorStats, found := stats[validatorId]
+		if !found {
+			failedSubmissions.WithLabelValues(id, signerKey, name).Set(0)
+			failedSubmissionsCost.WithLabelValues(id, signerKey, name).Set(0)
+			continue
+		}
+
+		failedSubmissions.WithLabelValues(id, signerKey, name).Set(float64(validatorStats.Count))
+		failedSubmissionsCost.WithLabelValues(id, signerKey, name).Set(utils.WeiToETH(validatorStats.Cost))
+		lastFailedSubmission.WithLabelValues(id, signerKey, name).Set(float64(validatorStats.LastTimestamp))
+	}
+
+	return nil
+}
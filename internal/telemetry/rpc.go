@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// rpcMessage is the part of a JSON-RPC request or response needed to label
+// the request.
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// failed returns true if the message is a response holding an error.
+func (m rpcMessage) failed() bool {
+	return len(m.Error) > 0 && string(m.Error) != "null"
+}
+
+// rpcTransport is an http.RoundTripper which counts and times every JSON-RPC
+// request sent through it, labelled by method and result.
+type rpcTransport struct {
+	endpoint string
+	next     http.RoundTripper
+}
+
+// NewRPCClient returns an http.Client which counts and times every JSON-RPC
+// request sent through it, labelled with the passed endpoint. Only requests
+// sent over HTTP are observed, not the ones sent over a websocket.
+func NewRPCClient(endpoint string) *http.Client {
+	return &http.Client{Transport: &rpcTransport{endpoint: endpoint, next: http.DefaultTransport}}
+}
+
+// RoundTrip sends the passed request, and observes each JSON-RPC call in it.
+// A call fails if the request fails, the response is not 200 OK, or the call
+// returns an error.
+func (t *rpcTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil {
+		return t.next.RoundTrip(request)
+	}
+
+	body, err := io.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	request = request.Clone(request.Context())
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	calls := decodeRPCMessages(body)
+	start := time.Now()
+	response, err := t.next.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		for _, call := range calls {
+			ObserveRPC(call.Method, t.endpoint, start, true)
+		}
+		return response, err
+	}
+
+	// read the response to find the calls which returned an error
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+	if err != nil {
+		for _, call := range calls {
+			ObserveRPC(call.Method, t.endpoint, start, true)
+		}
+		return response, err
+	}
+
+	// the responses to a batch might not be in the same order as the calls,
+	// so they are matched by id
+	failedIds := map[string]bool{}
+	for _, result := range decodeRPCMessages(responseBody) {
+		failedIds[string(result.ID)] = result.failed()
+	}
+	for _, call := range calls {
+		ObserveRPC(call.Method, t.endpoint, start, failedIds[string(call.ID)])
+	}
+
+	return response, nil
+}
+
+// decodeRPCMessages decodes a single JSON-RPC message or a batch of them,
+// returning nothing if it is neither.
+func decodeRPCMessages(data []byte) []rpcMessage {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		messages := []rpcMessage{}
+		if json.Unmarshal(data, &messages) != nil {
+			return nil
+		}
+		return messages
+	}
+
+	message := rpcMessage{}
+	if json.Unmarshal(data, &message) != nil {
+		return nil
+	}
+	return []rpcMessage{message}
+}
@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+)
+
+// the modes the sync loop can be in
+const (
+	SYNC_MODE_STARTING    = "starting"
+	SYNC_MODE_CATCHING_UP = "catching_up"
+	SYNC_MODE_FOLLOWING   = "following"
+	SYNC_MODE_PAUSED      = "paused"
+	SYNC_MODE_STOPPED     = "stopped"
+)
+
+// SyncState holds the state of the sync loop, as reported by the health and
+// status endpoints.
+type SyncState struct {
+	Mode               string
+	NextBlock          uint64
+	HeadBlock          uint64
+	HeadBlockLag       uint64
+	StartedAt          time.Time
+	LastSuccessfulLoop time.Time
+	LastError          string
+	LastErrorAt        time.Time
+	ConsecutiveErrors  int
+	TotalErrors        int
+	SignatureErrors    int
+	RPC                map[string]RPCState
+}
+
+// RPCState holds the state of the requests sent to an RPC endpoint.
+type RPCState struct {
+	Requests    int
+	Errors      int
+	LastSuccess time.Time
+	LastErrorAt time.Time
+	LastFailed  bool
+}
+
+var (
+	stateLock sync.Mutex
+	state     = SyncState{Mode: SYNC_MODE_STARTING, StartedAt: time.Now(), RPC: map[string]RPCState{}}
+)
+
+// GetSyncState returns a copy of the current state of the sync loop.
+func GetSyncState() SyncState {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	copied := state
+	copied.RPC = map[string]RPCState{}
+	for endpoint, rpcState := range state.RPC {
+		copied.RPC[endpoint] = rpcState
+	}
+	return copied
+}
+
+// SetSyncMode sets the mode the sync loop is in.
+func SetSyncMode(mode string) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.Mode = mode
+}
+
+// SetSyncProgress sets the next block to be processed and the head of the
+// chain, and the lag between them.
+func SetSyncProgress(nextBlock uint64, headBlock uint64) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.NextBlock = nextBlock
+	state.HeadBlock = headBlock
+	state.HeadBlockLag = 0
+	if headBlock >= nextBlock {
+		state.HeadBlockLag = headBlock - nextBlock + 1
+	}
+	headBlockLag.Set(float64(state.HeadBlockLag))
+}
+
+// RecordSyncSuccess records that the sync loop processed every block up to the
+// head of the chain without errors.
+func RecordSyncSuccess() {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.LastSuccessfulLoop = time.Now()
+	state.ConsecutiveErrors = 0
+	lastSuccessfulLoop.Set(float64(state.LastSuccessfulLoop.Unix()))
+}
+
+// RecordSyncError records that the sync loop failed with the passed error.
+func RecordSyncError(err error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.LastError = err.Error()
+	state.LastErrorAt = time.Now()
+	state.ConsecutiveErrors++
+	state.TotalErrors++
+}
+
+// RecordSignatureRecoveryErrors records the passed number of checkpoint
+// signatures whose signer could not be recovered.
+func RecordSignatureRecoveryErrors(count int) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.SignatureErrors += count
+	signatureRecoveryErrors.Add(float64(count))
+}
+
+// recordRPC records the result of a request sent to the passed endpoint.
+func recordRPC(endpoint string, failed bool) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	rpcState := state.RPC[endpoint]
+	rpcState.Requests++
+	rpcState.LastFailed = failed
+	if failed {
+		rpcState.Errors++
+		rpcState.LastErrorAt = time.Now()
+	} else {
+		rpcState.LastSuccess = time.Now()
+	}
+	state.RPC[endpoint] = rpcState
+}
@@ -0,0 +1,82 @@
+// Package telemetry holds the metrics about the monitor itself, as opposed to
+// the network it monitors. It only depends on Prometheus, so that it can be
+// used by every other package without an import cycle.
+package telemetry
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the endpoints RPC requests are labelled with
+const (
+	ENDPOINT_ETH = "eth"
+	ENDPOINT_BOR = "bor"
+)
+
+// the results RPC requests are labelled with
+const (
+	RESULT_SUCCESS = "success"
+	RESULT_ERROR   = "error"
+)
+
+var (
+	headBlockLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "head_block_lag",
+		Help: "The number of blocks between the head of the chain and the last block processed by the monitor.",
+	})
+
+	rpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_requests_total",
+		Help: "The number of RPC requests sent by the monitor.",
+	}, []string{"method", "endpoint", "result"})
+
+	rpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rpc_request_duration_seconds",
+		Help:    "The time taken by the RPC requests sent by the monitor. Requests sent in a batch are each observed with the duration of the whole batch.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"method", "endpoint", "result"})
+
+	CheckpointProcessingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "checkpoint_processing_duration_seconds",
+		Help:    "The time taken to process each checkpoint, from fetching its signatures to updating the metrics.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "The time taken by each database function, including opening the database.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+	}, []string{"query"})
+
+	signatureRecoveryErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "signature_recovery_errors_total",
+		Help: "The number of checkpoint signatures whose signer could not be recovered.",
+	})
+
+	lastSuccessfulLoop = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "last_successful_loop_timestamp",
+		Help: "The unix timestamp of the last time the monitor processed every block up to the head of the chain without errors.",
+	})
+)
+
+// ObserveRPC counts an RPC request with the passed method to the passed
+// endpoint, and observes its duration since the passed start.
+func ObserveRPC(method string, endpoint string, start time.Time, failed bool) {
+	result := RESULT_SUCCESS
+	if failed {
+		result = RESULT_ERROR
+	}
+
+	recordRPC(endpoint, failed)
+	rpcRequests.WithLabelValues(method, endpoint, result).Inc()
+	rpcRequestDuration.WithLabelValues(method, endpoint, result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDBQuery observes the duration of the passed database function since
+// the passed start. It is meant to be deferred at the start of the function.
+func ObserveDBQuery(query string, start time.Time) {
+	dbQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
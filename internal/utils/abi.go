@@ -2,7 +2,7 @@ package utils
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 	"sync"
@@ -40,6 +40,19 @@ func unpackDataAndSigs(payload []byte, abi abi.ABI) (data []byte, sigs [][3]*big
 	return
 }
 
+// totalStake returns the stake of a validator, being its own stake plus the
+// stake delegated to it.
+func totalStake(amount *big.Int, delegatedAmount *big.Int) *big.Int {
+	stake := new(big.Int)
+	if amount != nil {
+		stake.Add(stake, amount)
+	}
+	if delegatedAmount != nil {
+		stake.Add(stake, delegatedAmount)
+	}
+	return stake
+}
+
 // GetABI returns the contract's ABI struct from on its JSON representation
 func GetABI(data string) (abi.ABI, error) {
 	return abi.JSON(strings.NewReader(data))
@@ -56,7 +69,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	// pack the data for the query we are making
 	callData, err := stakeManagerABI.Pack("validators", big.NewInt(int64(validatorId)))
 	if err != nil {
-		fmt.Printf("ERR: Failed to pack data for StakeManager contract call (method: validators), error: %v\n", err)
+		slog.Error("Failed to pack data for StakeManager contract call (method: validators)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -88,7 +101,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Failed to query StakeManager contract (method: validators), error: %v\n", err)
+		slog.Error("Failed to query StakeManager contract (method: validators)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -113,7 +126,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	// unpack the response into the struct
 	err = stakeManagerABI.UnpackIntoInterface(&response, "validators", result)
 	if err != nil {
-		fmt.Printf("ERR: Failed to unpack the StakeManager contract call request (method: validators), error: %v\n", err)
+		slog.Error("Failed to unpack the StakeManager contract call request (method: validators)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -131,7 +144,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	// ownerOf on the smart contract
 	callData, err = stakeManagerABI.Pack("ownerOf", big.NewInt(int64(validatorId)))
 	if err != nil {
-		fmt.Printf("ERR: Failed to pack data for StakeManager contract call (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to pack data for StakeManager contract call (method: ownerOf)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -161,7 +174,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 
 	if err != nil {
 		if err.Error() == "execution reverted" {
-			fmt.Printf("WARN: Validator with ID %d has no owner.\n", validatorId)
+			slog.Warn("Validator has no owner", "validator_id", validatorId)
 			// it could be that the validator has no owner, such as validator
 			// with id = 11
 			// in this case, ignore the error
@@ -171,6 +184,9 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 				ActivationEpoch:   response.ActivationEpoch.Uint64(),
 				DeactivationEpoch: response.DeactivationEpoch.Uint64(),
 				SignerAddress:     response.Signer,
+				Stake:             totalStake(response.Amount, response.DelegatedAmount),
+				DelegatedStake:    response.DelegatedAmount,
+				CommissionRate:    response.CommissionRate.Uint64(),
 			}, Error: nil}
 			return
 
@@ -178,7 +194,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Failed to query StakeManager contract (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to query StakeManager contract (method: ownerOf)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -187,7 +203,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 	var ownerAddress common.Address
 	err = stakeManagerABI.UnpackIntoInterface(&ownerAddress, "ownerOf", result)
 	if err != nil {
-		fmt.Printf("ERR: Failed to unpack the StakeManager contract call request (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to unpack the StakeManager contract call request (method: ownerOf)", "validator_id", validatorId, "error", err)
 		validators <- ValidatorError{Validator: Validator{}, Error: err}
 		return
 	}
@@ -199,6 +215,7 @@ func GetValidatorInfoStakeManagerConcurrent(validatorId int, blockNumber int, st
 		DeactivationEpoch: response.DeactivationEpoch.Uint64(),
 		OwnerAddress:      ownerAddress,
 		SignerAddress:     response.Signer,
+		Stake:             totalStake(response.Amount, response.DelegatedAmount),
 	}, Error: nil}
 
 }
@@ -211,7 +228,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 	// pack the data for the query we are making
 	callData, err := stakeManagerABI.Pack("validators", big.NewInt(int64(validatorId)))
 	if err != nil {
-		fmt.Printf("ERR: Failed to pack data for StakeManager contract call (method: validators), error: %v\n", err)
+		slog.Error("Failed to pack data for StakeManager contract call (method: validators)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -240,7 +257,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Failed to query StakeManager contract (method: validators), error: %v\n", err)
+		slog.Error("Failed to query StakeManager contract (method: validators)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -264,7 +281,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 	// unpack the response into the struct
 	err = stakeManagerABI.UnpackIntoInterface(&response, "validators", result)
 	if err != nil {
-		fmt.Printf("ERR: Failed to unpack the StakeManager contract call request (method: validators), error: %v\n", err)
+		slog.Error("Failed to unpack the StakeManager contract call request (method: validators)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -279,7 +296,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 	// get the owner of the validator, using the validator id
 	callData, err = stakeManagerABI.Pack("ownerOf", big.NewInt(int64(validatorId)))
 	if err != nil {
-		fmt.Printf("ERR: Failed to pack data for StakeManager contract call (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to pack data for StakeManager contract call (method: ownerOf)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -308,7 +325,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 
 	if err != nil {
 		if err.Error() == "execution reverted" {
-			fmt.Printf("WARN: Validator with ID %d has no owner.\n", validatorId)
+			slog.Warn("Validator has no owner", "validator_id", validatorId)
 			// it could be that the validator has no owner, such as validator
 			// with id = 11
 			// in this case, ignore the error
@@ -318,13 +335,16 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 				ActivationEpoch:   response.ActivationEpoch.Uint64(),
 				DeactivationEpoch: response.DeactivationEpoch.Uint64(),
 				SignerAddress:     response.Signer,
+				Stake:             totalStake(response.Amount, response.DelegatedAmount),
+				DelegatedStake:    response.DelegatedAmount,
+				CommissionRate:    response.CommissionRate.Uint64(),
 			}, nil
 
 		}
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Failed to query StakeManager contract (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to query StakeManager contract (method: ownerOf)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -332,7 +352,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 	var ownerAddress common.Address
 	err = stakeManagerABI.UnpackIntoInterface(&ownerAddress, "ownerOf", result)
 	if err != nil {
-		fmt.Printf("ERR: Failed to unpack the StakeManager contract call request (method: ownerOf), error: %v\n", err)
+		slog.Error("Failed to unpack the StakeManager contract call request (method: ownerOf)", "validator_id", validatorId, "error", err)
 		return Validator{}, err
 	}
 
@@ -343,6 +363,7 @@ func GetValidatorInfoStakeManager(validatorId int, blockNumber int, stakeManager
 		DeactivationEpoch: response.DeactivationEpoch.Uint64(),
 		OwnerAddress:      ownerAddress,
 		SignerAddress:     response.Signer,
+		Stake:             totalStake(response.Amount, response.DelegatedAmount),
 	}, nil
 
 }
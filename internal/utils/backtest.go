@@ -0,0 +1,19 @@
+package utils
+
+// BacktestResult is the performance benchmark recomputed for a checkpoint,
+// with the window size and factor used. LivePerformanceBenchmark is the
+// value calculated by the monitor when the checkpoint was processed, if any.
+// Complete is false if the signer data of some validators might be missing
+// for part of the window, which is the case for checkpoints no longer in the
+// temporary table, unless all validators are tracked.
+type BacktestResult struct {
+	Checkpoint               int      `json:"checkpoint"`
+	Window                   int      `json:"window"`
+	Factor                   float64  `json:"factor"`
+	PerformanceBenchmark     float64  `json:"performance_benchmark"`
+	LivePerformanceBenchmark *float64 `json:"live_performance_benchmark"`
+	Median                   float64  `json:"median"`
+	Validators               int      `json:"validators"`
+	ValidatorsBelow          int      `json:"validators_below_performance_benchmark"`
+	Complete                 bool     `json:"complete"`
+}
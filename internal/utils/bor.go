@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"monitor/internal/telemetry"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BOR_HEADER_BATCH is the number of Bor block headers requested in a single
+// batch call.
+const BOR_HEADER_BATCH = 100
+
+// MAX_CHECKPOINT_BOR_BLOCKS is the largest range of Bor blocks a checkpoint
+// can cover, the same limit Bor applies when computing its root hash.
+const MAX_CHECKPOINT_BOR_BLOCKS = 32768
+
+// CheckpointVerification holds the result of recomputing the root hash of the
+// Bor blocks covered by a checkpoint, and comparing it with the submitted one.
+type CheckpointVerification struct {
+	CheckpointNumber  int         `json:"checkpoint"`
+	StartBlock        uint64      `json:"bor_start_block"`
+	EndBlock          uint64      `json:"bor_end_block"`
+	SubmittedRootHash common.Hash `json:"submitted_root_hash"`
+	ComputedRootHash  common.Hash `json:"computed_root_hash"`
+	Match             bool        `json:"match"`
+}
+
+// borHeader is the part of a Bor block header which the root hash of a
+// checkpoint commits to.
+type borHeader struct {
+	Number      *hexutil.Big   `json:"number"`
+	Time        hexutil.Uint64 `json:"timestamp"`
+	TxHash      common.Hash    `json:"transactionsRoot"`
+	ReceiptHash common.Hash    `json:"receiptsRoot"`
+}
+
+// DialBor returns a new rpc client connected to the Bor RPC in the config.
+func DialBor() (*rpc.Client, error) {
+	return rpc.DialOptions(context.Background(), GetConfig().BorRpcUrl, rpc.WithHTTPClient(telemetry.NewRPCClient(telemetry.ENDPOINT_BOR)))
+}
+
+// GetBorRootHash fetches the headers of the Bor blocks in the passed range,
+// inclusive, and computes their root hash the way Heimdall does for the
+// checkpoints covering them.
+func GetBorRootHash(startBlock uint64, endBlock uint64) (common.Hash, error) {
+	if endBlock < startBlock || endBlock-startBlock+1 > MAX_CHECKPOINT_BOR_BLOCKS {
+		return common.Hash{}, fmt.Errorf("invalid range of Bor blocks %d to %d", startBlock, endBlock)
+	}
+
+	// try to reach the Bor node
+	borRPCClient, err := DialBor()
+	if err != nil {
+		slog.Error("Unable to dial Bor node", "url", RedactURL(GetConfig().BorRpcUrl), "error", err)
+		return common.Hash{}, &DialError{GenericError{Message: "unable to dial Bor node"}}
+	}
+	defer borRPCClient.Close()
+
+	headers := make([]borHeader, 0, endBlock-startBlock+1)
+	for batchStart := startBlock; batchStart <= endBlock; batchStart += BOR_HEADER_BATCH {
+		batchEnd := min(batchStart+BOR_HEADER_BATCH-1, endBlock)
+
+		batch := []rpc.BatchElem{}
+		for blockNumber := batchStart; blockNumber <= batchEnd; blockNumber++ {
+			batch = append(batch, rpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeUint64(blockNumber), false},
+				Result: &borHeader{},
+			})
+		}
+
+		// retry call in case of failure
+		for i := 0; i < RETRIES; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+			err = borRPCClient.BatchCallContext(ctx, batch)
+			cancel()
+			if err == nil {
+				break
+			}
+			time.Sleep(time.Second * RETRY_WAIT)
+		}
+		if err != nil {
+			slog.Error("Error while fetching block headers from Bor rpc", "start_block", batchStart, "end_block", batchEnd, "error", err)
+			return common.Hash{}, &DialError{GenericError{Message: "error retrieving Bor block headers, error: " + err.Error()}}
+		}
+
+		for i, elem := range batch {
+			blockNumber := batchStart + uint64(i)
+			if elem.Error != nil {
+				return common.Hash{}, fmt.Errorf("error retrieving Bor block %d: %w", blockNumber, elem.Error)
+			}
+			header := elem.Result.(*borHeader)
+			if header.Number == nil || header.Number.ToInt().Uint64() != blockNumber {
+				return common.Hash{}, fmt.Errorf("Bor block %d not found", blockNumber)
+			}
+			headers = append(headers, *header)
+		}
+	}
+
+	return borRootHash(headers), nil
+}
+
+// borRootHash returns the root of the Merkle tree whose leaves are the hashes
+// of the passed headers, padded with empty leaves up to a power of two. Each
+// leaf hashes the block number, timestamp, transactions root and receipts root
+// of a header, and each node hashes its two children, without sorting them.
+func borRootHash(headers []borHeader) common.Hash {
+	size := 1
+	for size < len(headers) {
+		size *= 2
+	}
+
+	nodes := make([]common.Hash, size)
+	for i, header := range headers {
+		nodes[i] = crypto.Keccak256Hash(
+			common.LeftPadBytes(header.Number.ToInt().Bytes(), 32),
+			common.LeftPadBytes(new(big.Int).SetUint64(uint64(header.Time)).Bytes(), 32),
+			header.TxHash.Bytes(),
+			header.ReceiptHash.Bytes(),
+		)
+	}
+
+	for len(nodes) > 1 {
+		parents := make([]common.Hash, len(nodes)/2)
+		for i := range parents {
+			parents[i] = crypto.Keccak256Hash(nodes[2*i].Bytes(), nodes[2*i+1].Bytes())
+		}
+		nodes = parents
+	}
+
+	return nodes[0]
+}
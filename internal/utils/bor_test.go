@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testBorHeader returns the fixed header served by the Bor stand-in for the
+// passed block number.
+func testBorHeader(blockNumber uint64) borHeader {
+	return borHeader{
+		Number:      (*hexutil.Big)(new(big.Int).SetUint64(blockNumber)),
+		Time:        hexutil.Uint64(1700000000 + 2*blockNumber),
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(3 * blockNumber)),
+		ReceiptHash: common.BigToHash(new(big.Int).SetUint64(7 * blockNumber)),
+	}
+}
+
+// testBorLeaf returns the Merkle leaf of the fixed header of the passed block
+// number, built by hand from its fields.
+func testBorLeaf(blockNumber uint64) []byte {
+	return crypto.Keccak256(
+		common.LeftPadBytes(new(big.Int).SetUint64(blockNumber).Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(1700000000+2*blockNumber).Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(3*blockNumber).Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(7*blockNumber).Bytes(), 32),
+	)
+}
+
+// borStandIn is a stand-in for the JSON-RPC of a Bor node. It serves the fixed
+// headers of the blocks from first to last, except for the ones overridden in
+// headers, and records the size of every batch requested.
+type borStandIn struct {
+	first   uint64
+	last    uint64
+	headers map[uint64]borHeader
+
+	mutex   sync.Mutex
+	batches []int
+}
+
+func (s *borStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var requests []struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []any           `json:"params"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&requests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.batches = append(s.batches, len(requests))
+	s.mutex.Unlock()
+
+	responses := []map[string]any{}
+	for _, request := range requests {
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": nil}
+
+		blockNumber, err := hexutil.DecodeUint64(request.Params[0].(string))
+		if err == nil && request.Method == "eth_getBlockByNumber" && blockNumber >= s.first && blockNumber <= s.last {
+			header, found := s.headers[blockNumber]
+			if !found {
+				header = testBorHeader(blockNumber)
+			}
+			response["result"] = header
+		}
+		responses = append(responses, response)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// useBorStandIn starts the passed stand-in and points the config at it for the
+// rest of the test.
+func useBorStandIn(t *testing.T, standIn *borStandIn) {
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	previous := GetConfig()
+	SetConfig(GeneralSettings{BorRpcUrl: server.URL})
+	t.Cleanup(func() { SetConfig(*previous) })
+}
+
+func TestBorRootHash(t *testing.T) {
+	empty := make([]byte, 32)
+
+	tests := []struct {
+		name     string
+		blocks   []uint64
+		expected common.Hash
+	}{
+		{"one header", []uint64{10}, common.BytesToHash(testBorLeaf(10))},
+		{"two headers", []uint64{10, 11}, crypto.Keccak256Hash(testBorLeaf(10), testBorLeaf(11))},
+		{"padded to four", []uint64{10, 11, 12}, crypto.Keccak256Hash(
+			crypto.Keccak256(testBorLeaf(10), testBorLeaf(11)),
+			crypto.Keccak256(testBorLeaf(12), empty),
+		)},
+		{"padded to eight", []uint64{10, 11, 12, 13, 14}, crypto.Keccak256Hash(
+			crypto.Keccak256(crypto.Keccak256(testBorLeaf(10), testBorLeaf(11)), crypto.Keccak256(testBorLeaf(12), testBorLeaf(13))),
+			crypto.Keccak256(crypto.Keccak256(testBorLeaf(14), empty), crypto.Keccak256(empty, empty)),
+		)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers := []borHeader{}
+			for _, blockNumber := range test.blocks {
+				headers = append(headers, testBorHeader(blockNumber))
+			}
+
+			if rootHash := borRootHash(headers); rootHash != test.expected {
+				t.Errorf("borRootHash() = %s, expected %s", rootHash, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetBorRootHash(t *testing.T) {
+	// the root hash of the fixed headers of blocks 1000 to 1002
+	knownRootHash := common.HexToHash("0x4788a14c7e7b56e1c3b39b49909d957712b36ca5335c1d4a244376cf0654dc0f")
+
+	tests := []struct {
+		name    string
+		headers map[uint64]borHeader
+		match   bool
+	}{
+		{"match", nil, true},
+		{"mismatch", map[uint64]borHeader{1001: {
+			Number:      (*hexutil.Big)(big.NewInt(1001)),
+			Time:        hexutil.Uint64(1700002002),
+			TxHash:      common.HexToHash("0xdead"),
+			ReceiptHash: testBorHeader(1001).ReceiptHash,
+		}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useBorStandIn(t, &borStandIn{first: 1000, last: 1002, headers: test.headers})
+
+			rootHash, err := GetBorRootHash(1000, 1002)
+			if err != nil {
+				t.Fatalf("GetBorRootHash() returned error: %v", err)
+			}
+			if (rootHash == knownRootHash) != test.match {
+				t.Errorf("GetBorRootHash() = %s, known root hash %s, expected match %v", rootHash, knownRootHash, test.match)
+			}
+		})
+	}
+}
+
+func TestGetBorRootHashBatches(t *testing.T) {
+	standIn := &borStandIn{first: 1000, last: 1249}
+	useBorStandIn(t, standIn)
+
+	rootHash, err := GetBorRootHash(1000, 1249)
+	if err != nil {
+		t.Fatalf("GetBorRootHash() returned error: %v", err)
+	}
+
+	headers := []borHeader{}
+	for blockNumber := uint64(1000); blockNumber <= 1249; blockNumber++ {
+		headers = append(headers, testBorHeader(blockNumber))
+	}
+	if expected := borRootHash(headers); rootHash != expected {
+		t.Errorf("GetBorRootHash() = %s, expected %s", rootHash, expected)
+	}
+
+	expectedBatches := []int{BOR_HEADER_BATCH, BOR_HEADER_BATCH, 50}
+	if len(standIn.batches) != len(expectedBatches) {
+		t.Fatalf("requested batches of %v, expected %v", standIn.batches, expectedBatches)
+	}
+	for i, size := range expectedBatches {
+		if standIn.batches[i] != size {
+			t.Errorf("requested batches of %v, expected %v", standIn.batches, expectedBatches)
+		}
+	}
+}
+
+func TestGetBorRootHashMissingBlock(t *testing.T) {
+	useBorStandIn(t, &borStandIn{first: 1000, last: 1001})
+
+	_, err := GetBorRootHash(1000, 1002)
+	if err == nil {
+		t.Error("GetBorRootHash() returned no error for a block the Bor node does not have")
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CHECKPOINT_DATA_LENGTH is the length of the data passed to submitCheckpoint,
+// being six ABI encoded words.
+const CHECKPOINT_DATA_LENGTH = 6 * 32
+
+// CheckpointData holds the contents of a checkpoint, as passed to the
+// submitCheckpoint method of the Rootchain contract. StartBlock and EndBlock
+// are the first and last Bor blocks covered by the checkpoint, inclusive.
+type CheckpointData struct {
+	Proposer        common.Address
+	StartBlock      uint64
+	EndBlock        uint64
+	RootHash        common.Hash
+	AccountRootHash common.Hash
+	BorChainId      uint64
+}
+
+// BorBlocks returns the number of Bor blocks covered by the checkpoint.
+func (c CheckpointData) BorBlocks() uint64 {
+	if c.EndBlock < c.StartBlock {
+		return 0
+	}
+	return c.EndBlock - c.StartBlock + 1
+}
+
+// DecodeCheckpointData decodes the data passed to submitCheckpoint, which is
+// the ABI encoding of (address proposer, uint256 start, uint256 end, bytes32
+// rootHash, bytes32 accountRootHash, uint256 borChainId).
+func DecodeCheckpointData(data []byte) (CheckpointData, error) {
+	if len(data) < CHECKPOINT_DATA_LENGTH {
+		return CheckpointData{}, errors.New("checkpoint data is too short")
+	}
+
+	word := func(i int) []byte {
+		return data[i*32 : (i+1)*32]
+	}
+
+	// the numbers are uint256, but none of them is expected to overflow
+	numbers := [3]uint64{}
+	for i, index := range []int{1, 2, 5} {
+		number := new(big.Int).SetBytes(word(index))
+		if !number.IsUint64() {
+			return CheckpointData{}, errors.New("number in checkpoint data is too large")
+		}
+		numbers[i] = number.Uint64()
+	}
+
+	return CheckpointData{
+		Proposer:        common.BytesToAddress(word(0)),
+		StartBlock:      numbers[0],
+		EndBlock:        numbers[1],
+		RootHash:        common.BytesToHash(word(3)),
+		AccountRootHash: common.BytesToHash(word(4)),
+		BorChainId:      numbers[2],
+	}, nil
+}
@@ -0,0 +1,701 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+var configPath = "config/config.json"
+
+// currentConfig holds the config in use. It is replaced as a whole when the
+// config is reloaded, so readers should take one snapshot with GetConfig and
+// use it for the rest of the operation.
+var currentConfig atomic.Pointer[GeneralSettings]
+
+// ENV_PREFIX is the prefix of the environment variables which override values
+// in the config file.
+const ENV_PREFIX = "POLYMON_"
+
+// GeneralSettings is the representation of the options that can be
+// contained in the config file. The env tag holds the name of the environment
+// variable that overrides the respective option.
+type GeneralSettings struct {
+	ETHRpcUrl             string             `json:"ETHRpcUrl" yaml:"ETHRpcUrl" toml:"ETHRpcUrl" env:"ETH_RPC_URL"`
+	ETHRpcUrlFile         string             `json:"ETHRpcUrlFile" yaml:"ETHRpcUrlFile" toml:"ETHRpcUrlFile" env:"ETH_RPC_URL_FILE"`
+	ETHRpcHeaders         map[string]string  `json:"ETHRpcHeaders" yaml:"ETHRpcHeaders" toml:"ETHRpcHeaders" env:"ETH_RPC_HEADERS"`
+	PrometheusPort        string             `json:"PrometheusPort" yaml:"PrometheusPort" toml:"PrometheusPort" env:"PROMETHEUS_PORT"`
+	ListenAddress         string             `json:"ListenAddress" yaml:"ListenAddress" toml:"ListenAddress" env:"LISTEN_ADDRESS"`
+	TLSCertFile           string             `json:"TLSCertFile" yaml:"TLSCertFile" toml:"TLSCertFile" env:"TLS_CERT_FILE"`
+	TLSKeyFile            string             `json:"TLSKeyFile" yaml:"TLSKeyFile" toml:"TLSKeyFile" env:"TLS_KEY_FILE"`
+	AuthUsername          string             `json:"AuthUsername" yaml:"AuthUsername" toml:"AuthUsername" env:"AUTH_USERNAME"`
+	AuthPassword          string             `json:"AuthPassword" yaml:"AuthPassword" toml:"AuthPassword" env:"AUTH_PASSWORD"`
+	AuthToken             string             `json:"AuthToken" yaml:"AuthToken" toml:"AuthToken" env:"AUTH_TOKEN"`
+	PublicMetrics         bool               `json:"PublicMetrics" yaml:"PublicMetrics" toml:"PublicMetrics" env:"PUBLIC_METRICS"`
+	DatabaseLocation      string             `json:"DatabaseLocation" yaml:"DatabaseLocation" toml:"DatabaseLocation" env:"DATABASE_LOCATION"`
+	PublicKeys            []string           `json:"PublicKeys" yaml:"PublicKeys" toml:"PublicKeys" env:"PUBLIC_KEYS"`
+	Validators            []TrackedValidator `json:"Validators" yaml:"Validators" toml:"Validators" env:"VALIDATORS"`
+	ValidatorMetadataFile string             `json:"ValidatorMetadataFile" yaml:"ValidatorMetadataFile" toml:"ValidatorMetadataFile" env:"VALIDATOR_METADATA_FILE"`
+	PerformanceWindows    []string           `json:"PerformanceWindows" yaml:"PerformanceWindows" toml:"PerformanceWindows" env:"PERFORMANCE_WINDOWS"`
+	ContinueFromBlock     int                `json:"ContinueFromBlock" yaml:"ContinueFromBlock" toml:"ContinueFromBlock" env:"CONTINUE_FROM_BLOCK"`
+	LowBalanceThreshold   float64            `json:"LowBalanceThreshold" yaml:"LowBalanceThreshold" toml:"LowBalanceThreshold" env:"LOW_BALANCE_THRESHOLD"`
+	ScanFailedSubmissions bool               `json:"ScanFailedSubmissions" yaml:"ScanFailedSubmissions" toml:"ScanFailedSubmissions" env:"SCAN_FAILED_SUBMISSIONS"`
+	BorRpcUrl             string             `json:"BorRpcUrl" yaml:"BorRpcUrl" toml:"BorRpcUrl" env:"BOR_RPC_URL"`
+	StallMultiplier       float64            `json:"StallMultiplier" yaml:"StallMultiplier" toml:"StallMultiplier" env:"STALL_MULTIPLIER"`
+	MaxHeadLag            int                `json:"MaxHeadLag" yaml:"MaxHeadLag" toml:"MaxHeadLag" env:"MAX_HEAD_LAG"`
+	AdminToken            string             `json:"AdminToken" yaml:"AdminToken" toml:"AdminToken" env:"ADMIN_TOKEN"`
+	AdminTokenFile        string             `json:"AdminTokenFile" yaml:"AdminTokenFile" toml:"AdminTokenFile" env:"ADMIN_TOKEN_FILE"`
+	LogFormat             string             `json:"LogFormat" yaml:"LogFormat" toml:"LogFormat" env:"LOG_FORMAT"`
+	LogLevel              string             `json:"LogLevel" yaml:"LogLevel" toml:"LogLevel" env:"LOG_LEVEL"`
+
+	// ValidatorMetadata holds the entries read from ValidatorMetadataFile. It
+	// cannot be set directly.
+	ValidatorMetadata []TrackedValidator `json:"-" yaml:"-" toml:"-"`
+}
+
+// validatorMetadataFile is the representation of the file in
+// ValidatorMetadataFile. Its entries only add metadata, they do not make the
+// respective validators tracked.
+type validatorMetadataFile struct {
+	Validators []TrackedValidator `json:"Validators" yaml:"Validators" toml:"Validators"`
+}
+
+// updateConfigPath udpates the path to where the config file is located.
+func UpdateConfigPath(path string) {
+	configPath = path
+	SetConfig(openConfig(configPath))
+}
+
+// GetConfig returns the config currently in use. It is shared with every other
+// reader, so it must not be modified.
+func GetConfig() *GeneralSettings {
+	if config := currentConfig.Load(); config != nil {
+		return config
+	}
+	return &GeneralSettings{}
+}
+
+// SetConfig replaces the config in use with the passed one.
+func SetConfig(config GeneralSettings) {
+	currentConfig.Store(&config)
+}
+
+// openConfig opens the config specified in the path path. It returns the
+// parsed config as a GeneralSettings object, exiting if it is not valid.
+func openConfig(path string) GeneralSettings {
+	config, err := LoadConfig(path)
+	if err != nil {
+		slog.Error("Error while loading config file", "path", path, "error", err)
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// LoadConfig reads, parses and validates the config in the passed path,
+// without applying it. The format of the file (JSON, YAML or TOML) is
+// determined by its extension, and values set in POLYMON_* environment
+// variables take priority over the ones in the file. If the file does not
+// exist, the config is made up of environment variables only, if any are set.
+func LoadConfig(path string) (GeneralSettings, error) {
+	var config GeneralSettings
+
+	// read the file
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !(os.IsNotExist(err) && envOverridesSet()) {
+			return GeneralSettings{}, err
+		}
+		slog.Warn("Config file does not exist, using environment variables only", "path", path)
+	} else {
+		config, err = decodeConfig(path, content)
+		if err != nil {
+			return GeneralSettings{}, err
+		}
+	}
+
+	err = applyEnvOverrides(&config)
+	if err != nil {
+		return GeneralSettings{}, err
+	}
+
+	err = readSecretFiles(&config)
+	if err != nil {
+		return GeneralSettings{}, err
+	}
+
+	err = readValidatorMetadataFile(&config)
+	if err != nil {
+		return GeneralSettings{}, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return GeneralSettings{}, err
+	}
+
+	return config, nil
+}
+
+// decodeConfig parses the content of the config file, based on the extension
+// of its path. Unknown options are reported as errors, as they are most likely
+// typos.
+func decodeConfig(path string, content []byte) (GeneralSettings, error) {
+	var config GeneralSettings
+
+	err := decodeFile(path, content, &config)
+	if err != nil {
+		return GeneralSettings{}, err
+	}
+
+	return config, nil
+}
+
+// decodeFile parses the content of a JSON, YAML or TOML file into the passed
+// value, based on the extension of its path.
+func decodeFile(path string, content []byte, value any) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+
+		err := decoder.Decode(value)
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return newConfigError(typeErr.Field, "expected a value of type %s, got %s", typeErr.Type, typeErr.Value)
+			}
+			return err
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+
+		err := decoder.Decode(value)
+		if err != nil && err != io.EOF {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				return yamlConfigErrors(content, typeErr)
+			}
+			return err
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(content), value)
+		if err != nil {
+			return tomlConfigError(err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return newConfigError(undecoded[0].String(), "unknown option")
+		}
+	default:
+		return fmt.Errorf("unsupported file extension %q, expected .json, .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+// yamlErrorLine matches each of the errors in a yaml.TypeError, which start
+// with the line they refer to.
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// tomlErrorKey matches the key named in TOML decoding errors which are not a
+// toml.ParseError, such as type mismatches.
+var tomlErrorKey = regexp.MustCompile(`\(last key "([^"]*)"\): (.*)$`)
+
+// yamlConfigErrors converts the errors in the passed yaml.TypeError to a
+// ConfigError each, naming the option on the line the error refers to.
+func yamlConfigErrors(content []byte, typeErr *yaml.TypeError) error {
+	var root yaml.Node
+	if yaml.Unmarshal(content, &root) != nil {
+		return typeErr
+	}
+
+	errs := []error{}
+	for _, message := range typeErr.Errors {
+		match := yamlErrorLine.FindStringSubmatch(message)
+		if match == nil {
+			errs = append(errs, errors.New(message))
+			continue
+		}
+		line, _ := strconv.Atoi(match[1])
+		field := yamlFieldAt(&root, line, "")
+		if field == "" {
+			errs = append(errs, errors.New(message))
+			continue
+		}
+
+		if strings.HasPrefix(match[2], "field ") && strings.Contains(match[2], " not found in type ") {
+			errs = append(errs, newConfigError(field, "unknown option"))
+		} else {
+			errs = append(errs, newConfigError(field, "%s", match[2]))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// yamlFieldAt returns the path of the option on the passed line of a YAML
+// document, e.g. Validators.ID, or an empty string if there is none. Like the
+// JSON field paths, it does not include indexes into lists.
+func yamlFieldAt(node *yaml.Node, line int, path string) string {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if field := yamlFieldAt(child, line, path); field != "" {
+				return field
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field := key.Value
+			if path != "" {
+				field = path + "." + key.Value
+			}
+
+			if key.Line == line || (value.Kind == yaml.ScalarNode && value.Line == line) {
+				return field
+			}
+			if nested := yamlFieldAt(value, line, field); nested != "" {
+				return nested
+			}
+		}
+	}
+	return ""
+}
+
+// tomlConfigError converts the passed TOML decoding error to a ConfigError
+// naming the option it refers to, if known.
+func tomlConfigError(err error) error {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		if parseErr.LastKey == "" {
+			return err
+		}
+		message := parseErr.Message
+		if message == "" {
+			message = strings.TrimPrefix(parseErr.Error(), fmt.Sprintf("toml: line %d (last key %q): ", parseErr.Position.Line, parseErr.LastKey))
+		}
+		return newConfigError(parseErr.LastKey, "%s", message)
+	}
+
+	if match := tomlErrorKey.FindStringSubmatch(err.Error()); match != nil {
+		return newConfigError(match[1], "%s", match[2])
+	}
+	return err
+}
+
+// envOverridesSet returns true if any of the environment variables that
+// override the config are set.
+func envOverridesSet() bool {
+	configType := reflect.TypeOf(GeneralSettings{})
+	for i := 0; i < configType.NumField(); i++ {
+		envName := configType.Field(i).Tag.Get("env")
+		if envName == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(ENV_PREFIX + envName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readSecretFiles replaces options which are set to be read from a separate
+// file, such as the ETH RPC URL and the admin token, with the content of said
+// file.
+func readSecretFiles(config *GeneralSettings) error {
+	if config.ETHRpcUrlFile != "" {
+		if config.ETHRpcUrl != "" {
+			return newConfigError("ETHRpcUrlFile", "cannot be used together with ETHRpcUrl")
+		}
+
+		content, err := os.ReadFile(config.ETHRpcUrlFile)
+		if err != nil {
+			return newConfigError("ETHRpcUrlFile", "%v", err)
+		}
+		config.ETHRpcUrl = strings.TrimSpace(string(content))
+	}
+
+	if config.AdminTokenFile != "" {
+		if config.AdminToken != "" {
+			return newConfigError("AdminTokenFile", "cannot be used together with AdminToken")
+		}
+
+		content, err := os.ReadFile(config.AdminTokenFile)
+		if err != nil {
+			return newConfigError("AdminTokenFile", "%v", err)
+		}
+		config.AdminToken = strings.TrimSpace(string(content))
+	}
+
+	return nil
+}
+
+// readValidatorMetadataFile reads the validator metadata (names, teams and
+// groups) from ValidatorMetadataFile, if set. The file has the same format as
+// the Validators option, under a top level "Validators" key.
+func readValidatorMetadataFile(config *GeneralSettings) error {
+	if config.ValidatorMetadataFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(config.ValidatorMetadataFile)
+	if err != nil {
+		return newConfigError("ValidatorMetadataFile", "%v", err)
+	}
+
+	var metadata validatorMetadataFile
+	err = decodeFile(config.ValidatorMetadataFile, content, &metadata)
+	if err != nil {
+		return newConfigError("ValidatorMetadataFile", "%v", err)
+	}
+	config.ValidatorMetadata = metadata.Validators
+
+	return nil
+}
+
+// applyEnvOverrides replaces the values in the config with the ones in the
+// respective POLYMON_* environment variables, if set. Lists are comma
+// separated, and maps are comma separated key=value pairs.
+func applyEnvOverrides(config *GeneralSettings) error {
+	configValue := reflect.ValueOf(config).Elem()
+	configType := configValue.Type()
+
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if field.Tag.Get("env") == "" {
+			continue
+		}
+		envName := ENV_PREFIX + field.Tag.Get("env")
+
+		envValue, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+
+		value := configValue.Field(i)
+		switch value.Kind() {
+		case reflect.String:
+			value.SetString(envValue)
+		case reflect.Int:
+			number, err := strconv.Atoi(envValue)
+			if err != nil {
+				return newConfigError(field.Name, "%s must be a whole number, got %q", envName, envValue)
+			}
+			value.SetInt(int64(number))
+		case reflect.Float64:
+			number, err := strconv.ParseFloat(envValue, 64)
+			if err != nil {
+				return newConfigError(field.Name, "%s must be a number, got %q", envName, envValue)
+			}
+			value.SetFloat(number)
+		case reflect.Bool:
+			enabled, err := strconv.ParseBool(envValue)
+			if err != nil {
+				return newConfigError(field.Name, "%s must be true or false, got %q", envName, envValue)
+			}
+			value.SetBool(enabled)
+		case reflect.Slice:
+			items := []string{}
+			for _, item := range strings.Split(envValue, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+
+			// tracked validators are passed as e.g. id:123,owner:0x..
+			if value.Type() == reflect.TypeOf([]TrackedValidator{}) {
+				validators := []TrackedValidator{}
+				for _, item := range items {
+					validator, err := ParseTrackedValidator(item)
+					if err != nil {
+						return newConfigError(field.Name, "%s: %v", envName, err)
+					}
+					validators = append(validators, validator)
+				}
+				value.Set(reflect.ValueOf(validators))
+				continue
+			}
+
+			value.Set(reflect.ValueOf(items))
+		case reflect.Map:
+			items := map[string]string{}
+			for _, item := range strings.Split(envValue, ",") {
+				key, itemValue, found := strings.Cut(item, "=")
+				if !found {
+					return newConfigError(field.Name, "%s must be a list of comma separated key=value pairs", envName)
+				}
+				items[strings.TrimSpace(key)] = strings.TrimSpace(itemValue)
+			}
+			value.Set(reflect.ValueOf(items))
+		default:
+			return newConfigError(field.Name, "cannot be set using %s", envName)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the values in the config are usable. It returns all the
+// problems found, each as a ConfigError naming the respective field.
+func (config GeneralSettings) Validate() error {
+	var errs []error
+
+	if config.ETHRpcUrl == "" {
+		errs = append(errs, newConfigError("ETHRpcUrl", "must not be empty, unless ETHRpcUrlFile is set"))
+	} else if err := validateRpcUrl("ETHRpcUrl", config.ETHRpcUrl); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the Bor RPC is optional, and only used to verify checkpoints
+	if config.BorRpcUrl != "" {
+		if err := validateRpcUrl("BorRpcUrl", config.BorRpcUrl); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for key := range config.ETHRpcHeaders {
+		if key == "" || strings.ContainsAny(key, " :\r\n") {
+			errs = append(errs, newConfigError("ETHRpcHeaders", "%q is not a valid header name", key))
+		}
+	}
+
+	port, err := strconv.Atoi(config.PrometheusPort)
+	if err != nil || port < 1 || port > 65535 {
+		errs = append(errs, newConfigError("PrometheusPort", "%q is not a valid port number", config.PrometheusPort))
+	}
+
+	// the address is only the host, as the port is set separately
+	if config.ListenAddress != "" && strings.Contains(config.ListenAddress, ":") && net.ParseIP(config.ListenAddress) == nil {
+		errs = append(errs, newConfigError("ListenAddress", "%q is not a valid host or IP address, it must not include the port", config.ListenAddress))
+	}
+
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		errs = append(errs, newConfigError("TLSCertFile", "must be set together with TLSKeyFile"))
+	} else if config.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile); err != nil {
+			errs = append(errs, newConfigError("TLSCertFile", "%v", err))
+		}
+	}
+
+	if (config.AuthUsername == "") != (config.AuthPassword == "") {
+		errs = append(errs, newConfigError("AuthUsername", "must be set together with AuthPassword"))
+	}
+
+	if config.AuthToken != "" && len(config.AuthToken) < MIN_TOKEN_LENGTH {
+		errs = append(errs, newConfigError("AuthToken", "must be at least %d characters long", MIN_TOKEN_LENGTH))
+	}
+
+	if config.DatabaseLocation == "" {
+		errs = append(errs, newConfigError("DatabaseLocation", "must not be empty"))
+	}
+
+	for i, publicKey := range config.PublicKeys {
+		if publicKey == "*" {
+			if len(config.PublicKeys) != 1 {
+				errs = append(errs, newConfigError(fmt.Sprintf("PublicKeys[%d]", i), "\"*\" must be the only entry"))
+			}
+			continue
+		}
+		if !common.IsHexAddress(publicKey) {
+			errs = append(errs, newConfigError(fmt.Sprintf("PublicKeys[%d]", i), "%q is not a valid address", publicKey))
+		}
+	}
+
+	for i, validator := range config.Validators {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, newConfigError(fmt.Sprintf("Validators[%d]", i), "%v", err))
+		}
+	}
+
+	for i, validator := range config.ValidatorMetadata {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, newConfigError(fmt.Sprintf("ValidatorMetadataFile: Validators[%d]", i), "%v", err))
+		}
+	}
+
+	windowNames := []string{}
+	for i, name := range config.PerformanceWindows {
+		window, err := ParsePerformanceWindow(name)
+		if err != nil {
+			errs = append(errs, newConfigError(fmt.Sprintf("PerformanceWindows[%d]", i), "%v", err))
+			continue
+		}
+		if ContainsString(windowNames, window.Name) {
+			if window.Name == name {
+				errs = append(errs, newConfigError(fmt.Sprintf("PerformanceWindows[%d]", i), "window %q is listed more than once", name))
+			} else {
+				errs = append(errs, newConfigError(fmt.Sprintf("PerformanceWindows[%d]", i), "window %q is listed more than once, as %q", name, window.Name))
+			}
+		}
+		windowNames = append(windowNames, window.Name)
+	}
+
+	if config.ContinueFromBlock < 0 {
+		errs = append(errs, newConfigError("ContinueFromBlock", "must not be negative"))
+	}
+
+	if config.LowBalanceThreshold < 0 {
+		errs = append(errs, newConfigError("LowBalanceThreshold", "must not be negative"))
+	}
+
+	// 0 means the default
+	if config.StallMultiplier != 0 && config.StallMultiplier < 1 {
+		errs = append(errs, newConfigError("StallMultiplier", "must be at least 1"))
+	}
+
+	if config.MaxHeadLag < 0 {
+		errs = append(errs, newConfigError("MaxHeadLag", "must not be negative"))
+	}
+
+	// the admin API is disabled without a token
+	if config.AdminToken != "" && len(config.AdminToken) < MIN_TOKEN_LENGTH {
+		errs = append(errs, newConfigError("AdminToken", "must be at least %d characters long", MIN_TOKEN_LENGTH))
+	}
+
+	if _, err := ParseLogLevel(config.LogLevel); err != nil {
+		errs = append(errs, newConfigError("LogLevel", "%v", err))
+	}
+
+	if _, err := newLogHandler(config.LogFormat, io.Discard); err != nil {
+		errs = append(errs, newConfigError("LogFormat", "%v", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// newConfigError returns a ConfigError for the passed field, with a formatted
+// message.
+func newConfigError(field string, format string, args ...any) *ConfigError {
+	return &ConfigError{GenericError: GenericError{Message: fmt.Sprintf(format, args...)}, Field: field}
+}
+
+// ReloadConfig loads the config from the path it was originally loaded from and
+// replaces the current one with it. Settings which can only be applied on
+// startup are kept as they are. It returns the previous config.
+func ReloadConfig() (GeneralSettings, error) {
+	current := GetConfig()
+
+	newConfig, err := LoadConfig(configPath)
+	if err != nil {
+		return *current, err
+	}
+
+	if newConfig.DatabaseLocation != current.DatabaseLocation {
+		slog.Warn("DatabaseLocation cannot be changed without a restart, keeping the current value", "path", current.DatabaseLocation)
+		newConfig.DatabaseLocation = current.DatabaseLocation
+	}
+
+	if newConfig.PrometheusPort != current.PrometheusPort {
+		slog.Warn("PrometheusPort cannot be changed without a restart, keeping the current value", "port", current.PrometheusPort)
+		newConfig.PrometheusPort = current.PrometheusPort
+	}
+
+	if newConfig.ListenAddress != current.ListenAddress {
+		slog.Warn("ListenAddress cannot be changed without a restart, keeping the current value", "address", current.ListenAddress)
+		newConfig.ListenAddress = current.ListenAddress
+	}
+
+	// the certificate can be replaced, but TLS cannot be turned on or off
+	if (newConfig.TLSCertFile == "") != (current.TLSCertFile == "") {
+		slog.Warn("TLS cannot be enabled or disabled without a restart, keeping the current certificate", "cert", current.TLSCertFile)
+		newConfig.TLSCertFile, newConfig.TLSKeyFile = current.TLSCertFile, current.TLSKeyFile
+	}
+
+	// ContinueFromBlock is only used on startup
+	newConfig.ContinueFromBlock = current.ContinueFromBlock
+
+	SetConfig(newConfig)
+
+	return *current, nil
+}
+
+// SameKeys returns true if both slices contain the same keys, regardless of
+// their order or case.
+func SameKeys(keys1 []string, keys2 []string) bool {
+	if len(keys1) != len(keys2) {
+		return false
+	}
+	for _, key := range keys1 {
+		if !ContainsString(keys2, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConfigPath returns the path to the config file currently in use.
+func ConfigPath() string {
+	return configPath
+}
+
+// validateRpcUrl checks that the passed RPC URL can be dialled, returning a
+// ConfigError naming the passed field if not.
+func validateRpcUrl(field string, rawUrl string) error {
+	rpcUrl, err := url.Parse(rawUrl)
+	if err != nil || rpcUrl.Host == "" {
+		return newConfigError(field, "not a valid URL")
+	}
+	if !ContainsString([]string{"http", "https", "ws", "wss"}, rpcUrl.Scheme) {
+		return newConfigError(field, "unsupported scheme %q, expected http, https, ws or wss", rpcUrl.Scheme)
+	}
+	return nil
+}
+
+// ListenAddr returns the address the metrics and API listener binds to, made
+// up of ListenAddress and PrometheusPort. An empty ListenAddress binds to all
+// interfaces.
+func ListenAddr() string {
+	config := GetConfig()
+	return net.JoinHostPort(config.ListenAddress, config.PrometheusPort)
+}
+
+// LowBalanceThreshold returns the ETH balance below which a signer is
+// considered to be running low, as configured, or the default if not set.
+func LowBalanceThreshold() float64 {
+	config := GetConfig()
+	if config.LowBalanceThreshold == 0 {
+		return DEFAULT_LOW_BALANCE_THRESHOLD
+	}
+	return config.LowBalanceThreshold
+}
+
+// StallMultiplier returns how many times the average checkpoint interval can
+// pass without a new checkpoint before checkpointing is considered stalled, as
+// configured, or the default if not set.
+func StallMultiplier() float64 {
+	config := GetConfig()
+	if config.StallMultiplier == 0 {
+		return DEFAULT_STALL_MULTIPLIER
+	}
+	return config.StallMultiplier
+}
+
+// MaxHeadLag returns the number of blocks the monitor can be behind the head
+// of the chain while still being ready, as configured, or the default if not
+// set.
+func MaxHeadLag() int {
+	config := GetConfig()
+	if config.MaxHeadLag == 0 {
+		return DEFAULT_MAX_HEAD_LAG
+	}
+	return config.MaxHeadLag
+}
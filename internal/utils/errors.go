@@ -31,6 +31,24 @@ type NoLogsFoundError struct {
 	GenericError
 }
 
+// ConfigError is used when an option in the config is not valid. Field holds
+// the name of the respective option.
+type ConfigError struct {
+	GenericError
+	Field string
+}
+
+// Error returns the name of the field followed by the message.
+func (e *ConfigError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// AdminCommandError is used when an admin command cannot be run in the
+// current state of the monitor, e.g. rescanning blocks not processed yet.
+type AdminCommandError struct {
+	GenericError
+}
+
 // Database related errors:
 
 // ValidatorNotFoundError is used when the validator is not found in a database
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogLevel holds the level of the logger. It can be changed at runtime, and
+// the change takes effect immediately for all log lines.
+var LogLevel = new(slog.LevelVar)
+
+// ParseLogLevel converts a level name (debug, info, warn or error) to the
+// respective slog level.
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// newLogHandler returns the slog handler for the passed format, which can be
+// either text or json, writing to w.
+func newLogHandler(format string, w io.Writer) (slog.Handler, error) {
+	options := &slog.HandlerOptions{Level: LogLevel, ReplaceAttr: redactAttr}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.NewTextHandler(w, options), nil
+	case "json":
+		return slog.NewJSONHandler(w, options), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// SetupLogger sets the default logger used throughout the tool, with the
+// passed format and level.
+func SetupLogger(format string, level string) error {
+	parsedLevel, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	handler, err := newLogHandler(format, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	LogLevel.Set(parsedLevel)
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
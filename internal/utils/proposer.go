@@ -0,0 +1,26 @@
+package utils
+
+import "math/big"
+
+// ProposerStats holds the number of checkpoints proposed by a validator over
+// a range of checkpoints, and the sum of their rewards in wei. Checkpoints
+// processed before rewards were stored in full are counted, but their reward
+// is not included. RewardUnknown is the number of such checkpoints.
+type ProposerStats struct {
+	Proposed      int
+	RewardUnknown int
+	Reward        *big.Int
+}
+
+// WeiToPOL converts the passed amount in wei to POL. The result is only
+// approximate, but precise enough to be exported as a metric.
+func WeiToPOL(wei *big.Int) float64 {
+	pol, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return pol
+}
+
+// WeiToETH converts the passed amount in wei to ETH. As both have 18
+// decimals, it is the same as WeiToPOL.
+func WeiToETH(wei *big.Int) float64 {
+	return WeiToPOL(wei)
+}
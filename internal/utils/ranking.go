@@ -0,0 +1,66 @@
+package utils
+
+import "sort"
+
+// ValidatorPerformance holds the performance of a validator over a range of
+// checkpoints. Active is true if the validator is counted in the performance
+// benchmark.
+type ValidatorPerformance struct {
+	Validator   Validator
+	Signed      int
+	Checkpoints int
+	Performance float64
+	Active      bool
+}
+
+// ValidatorRank holds the position of a validator within a set, by
+// performance. A rank of 1 is the best performance, and validators with the
+// same performance share the same rank. The percentile is the percentage of
+// the set with a lower performance, counting validators with the same
+// performance as half.
+type ValidatorRank struct {
+	ValidatorId int
+	Performance float64
+	Rank        int
+	Percentile  float64
+}
+
+// RankPerformance ranks the validators in the passed map of performance, keyed
+// by validator id. The results are ordered from the best performance to the
+// worst, and by validator id for the same performance.
+func RankPerformance(performance map[int]float64) []ValidatorRank {
+	ranks := []ValidatorRank{}
+	for validatorId, value := range performance {
+		ranks = append(ranks, ValidatorRank{ValidatorId: validatorId, Performance: value})
+	}
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Performance != ranks[j].Performance {
+			return ranks[i].Performance > ranks[j].Performance
+		}
+		return ranks[i].ValidatorId < ranks[j].ValidatorId
+	})
+
+	for i := range ranks {
+		// validators with the same performance share the rank of the first
+		if i > 0 && ranks[i].Performance == ranks[i-1].Performance {
+			ranks[i].Rank = ranks[i-1].Rank
+		} else {
+			ranks[i].Rank = i + 1
+		}
+	}
+
+	for i := range ranks {
+		below, same := 0, 0
+		for _, other := range ranks {
+			if other.Performance < ranks[i].Performance {
+				below++
+			} else if other.Performance == ranks[i].Performance {
+				same++
+			}
+		}
+		ranks[i].Percentile = (float64(below) + float64(same)/2) / float64(len(ranks)) * 100
+	}
+
+	return ranks
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// REDACTED replaces secrets in log lines and any other output.
+const REDACTED = "REDACTED"
+
+// minSecretLength is the minimum length of a path segment, query value or
+// header value for it to be considered an API key. Providers usually use keys
+// of 32 characters or more (e.g. /v3/<key>), while regular values are short.
+// Shorter values are not searched for in the output, as they would replace
+// unrelated text, but they are still hidden when the URL is redacted.
+const minSecretLength = 16
+
+// RedactURL returns the passed URL with its secret parts replaced: the
+// password, the values of query parameters and any path segment which looks
+// like an API key. The scheme and host are kept, so that the URL can still be
+// told apart from others.
+func RedactURL(rawUrl string) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil || parsedUrl.Host == "" {
+		// if we cannot tell which part is secret, hide all of it
+		return REDACTED
+	}
+
+	if parsedUrl.User != nil {
+		if _, hasPassword := parsedUrl.User.Password(); hasPassword {
+			parsedUrl.User = url.UserPassword(parsedUrl.User.Username(), REDACTED)
+		}
+	}
+
+	query := parsedUrl.Query()
+	for key := range query {
+		query.Set(key, REDACTED)
+	}
+	parsedUrl.RawQuery = query.Encode()
+
+	segments := strings.Split(parsedUrl.Path, "/")
+	for i, segment := range segments {
+		if len(segment) >= minSecretLength {
+			segments[i] = REDACTED
+		}
+	}
+	parsedUrl.Path = strings.Join(segments, "/")
+	parsedUrl.RawPath = ""
+
+	return parsedUrl.String()
+}
+
+// configSecrets returns all the secret values in the passed config, which must never
+// appear in any output.
+func configSecrets(config *GeneralSettings) []string {
+	secrets := []string{}
+
+	for _, rawUrl := range []string{config.ETHRpcUrl, config.BorRpcUrl} {
+		secrets = append(secrets, urlSecrets(rawUrl)...)
+	}
+
+	for _, value := range config.ETHRpcHeaders {
+		// also look for the credentials on their own, without the scheme of
+		// Authorization headers (e.g. Bearer <token>)
+		for _, secret := range append([]string{value}, strings.Fields(value)...) {
+			if len(secret) >= minSecretLength {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+
+	for _, value := range []string{config.AdminToken, config.AuthPassword, config.AuthToken} {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+
+	return secrets
+}
+
+// urlSecrets returns the passed URL along with the parts of it which might be
+// secret, being the password, long query values and long path segments.
+func urlSecrets(rawUrl string) []string {
+	if rawUrl == "" {
+		return nil
+	}
+	secrets := []string{rawUrl}
+
+	parsedUrl, err := url.Parse(rawUrl)
+	if err == nil {
+		if password, hasPassword := parsedUrl.User.Password(); hasPassword {
+			secrets = append(secrets, password)
+		}
+		for _, values := range parsedUrl.Query() {
+			for _, value := range values {
+				if len(value) >= minSecretLength {
+					secrets = append(secrets, value)
+				}
+			}
+		}
+		for _, segment := range strings.Split(parsedUrl.Path, "/") {
+			if len(segment) >= minSecretLength {
+				secrets = append(secrets, segment)
+			}
+		}
+	}
+
+	return secrets
+}
+
+// RedactSecrets replaces every secret from the config that appears in the
+// passed text. The full RPC URL is replaced with its redacted form, while any
+// other secret is replaced with REDACTED.
+func RedactSecrets(text string) string {
+	config := GetConfig()
+
+	for _, rawUrl := range []string{config.ETHRpcUrl, config.BorRpcUrl} {
+		if rawUrl != "" {
+			text = strings.ReplaceAll(text, rawUrl, RedactURL(rawUrl))
+		}
+	}
+
+	for _, secret := range configSecrets(config) {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, REDACTED)
+		}
+	}
+
+	return text
+}
+
+// redactAttr is used by the logger to remove secrets from the message and all
+// the values of each log line.
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(RedactSecrets(value.String()))
+	case slog.KindAny:
+		switch anyValue := value.Any().(type) {
+		case error:
+			attr.Value = slog.StringValue(RedactSecrets(anyValue.Error()))
+		case fmt.Stringer:
+			attr.Value = slog.StringValue(RedactSecrets(anyValue.String()))
+		case []string:
+			redacted := make([]string, len(anyValue))
+			for i, item := range anyValue {
+				redacted[i] = RedactSecrets(item)
+			}
+			attr.Value = slog.AnyValue(redacted)
+		}
+	}
+
+	return attr
+}
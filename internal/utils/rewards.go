@@ -0,0 +1,61 @@
+package utils
+
+import "math/big"
+
+// ValidatorStake holds the stake of a validator at a point in time, in wei,
+// of which DelegatedStake was delegated to it, along with the percentage of
+// the delegators' rewards kept by the validator.
+type ValidatorStake struct {
+	Stake          *big.Int
+	DelegatedStake *big.Int
+	CommissionRate uint64
+}
+
+// EstimatedReward holds the estimated rewards of a validator, in wei, over one
+// or more checkpoints. Reward is everything earned through the validator's
+// stake, of which DelegatorReward went to its delegators after commission.
+// DelegatorRewardPerPOL is what each POL delegated to the validator earned,
+// i.e. DelegatorReward divided by the delegated stake at each checkpoint.
+// MissedReward is the reward the validator would have earned on the
+// checkpoints it did not sign.
+type EstimatedReward struct {
+	ValidatorId           int
+	Reward                *big.Int
+	DelegatorReward       *big.Int
+	DelegatorRewardPerPOL *big.Int
+	MissedReward          *big.Int
+}
+
+// NewEstimatedReward returns an EstimatedReward of 0 for the passed validator.
+func NewEstimatedReward(validatorId int) EstimatedReward {
+	return EstimatedReward{ValidatorId: validatorId, Reward: new(big.Int), DelegatorReward: new(big.Int), DelegatorRewardPerPOL: new(big.Int), MissedReward: new(big.Int)}
+}
+
+// Add adds the passed rewards to the estimated rewards.
+func (r EstimatedReward) Add(other EstimatedReward) {
+	r.Reward.Add(r.Reward, other.Reward)
+	r.DelegatorReward.Add(r.DelegatorReward, other.DelegatorReward)
+	r.DelegatorRewardPerPOL.Add(r.DelegatorRewardPerPOL, other.DelegatorRewardPerPOL)
+	r.MissedReward.Add(r.MissedReward, other.MissedReward)
+}
+
+// Sub subtracts the passed rewards from the estimated rewards.
+func (r EstimatedReward) Sub(other EstimatedReward) {
+	r.Reward.Sub(r.Reward, other.Reward)
+	r.DelegatorReward.Sub(r.DelegatorReward, other.DelegatorReward)
+	r.DelegatorRewardPerPOL.Sub(r.DelegatorRewardPerPOL, other.DelegatorRewardPerPOL)
+	r.MissedReward.Sub(r.MissedReward, other.MissedReward)
+}
+
+// Copy returns a copy of the estimated rewards, which can be modified without
+// affecting the original.
+func (r EstimatedReward) Copy() EstimatedReward {
+	copied := NewEstimatedReward(r.ValidatorId)
+	copied.Add(r)
+	return copied
+}
+
+// IsZero returns true if all the estimated rewards are 0.
+func (r EstimatedReward) IsZero() bool {
+	return r.Reward.Sign() == 0 && r.DelegatorReward.Sign() == 0 && r.DelegatorRewardPerPOL.Sign() == 0 && r.MissedReward.Sign() == 0
+}
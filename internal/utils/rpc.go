@@ -3,11 +3,13 @@ package utils
 import (
 	"context"
 	"errors"
-	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
+	"net/http"
 	"time"
 
+	"monitor/internal/telemetry"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -27,6 +29,27 @@ type NewHeaderBlockEvent struct {
 	BlockNumber     uint64
 }
 
+// TransactionCost holds the gas used by a transaction, the price paid per gas
+// and the resulting cost, both in wei.
+type TransactionCost struct {
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+	Cost              *big.Int
+}
+
+// DialETH connects to the ETH RPC in the config, sending the configured
+// headers (if any) with every request.
+func DialETH() (*rpc.Client, error) {
+	config := GetConfig()
+
+	headers := http.Header{}
+	for key, value := range config.ETHRpcHeaders {
+		headers.Set(key, value)
+	}
+
+	return rpc.DialOptions(context.Background(), config.ETHRpcUrl, rpc.WithHeaders(headers), rpc.WithHTTPClient(telemetry.NewRPCClient(telemetry.ENDPOINT_ETH)))
+}
+
 // GetCheckpointSignatures gets the data and signatures contained within a
 // submitCheckpoint method call. It requires the transaction hash of where the
 // method call occured.
@@ -36,9 +59,9 @@ func GetCheckpointSignatures(txHash common.Hash) ([]byte, [][3]*big.Int, error)
 	defer cancel()
 
 	// try to reach the ETH node
-	ethRPCClient, err := rpc.Dial(Config.ETHRpcUrl)
+	ethRPCClient, err := DialETH()
 	if err != nil {
-		log.Println("ERR: Unable to dial ETH node (", Config.ETHRpcUrl, "), error:", err)
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
 		return []byte{}, [][3]*big.Int{}, &DialError{GenericError{Message: "unable to dial ETH node"}}
 	}
 	ethClient := ethclient.NewClient(ethRPCClient)
@@ -46,10 +69,10 @@ func GetCheckpointSignatures(txHash common.Hash) ([]byte, [][3]*big.Int, error)
 	// get the transaction using the hash
 	tx, isPending, err := ethClient.TransactionByHash(ctx, txHash)
 	if err != nil {
-		log.Println("ERR: Error while fetching transaction by hash from ETH rpc, error:", err)
+		slog.Error("Error while fetching transaction by hash from ETH rpc", "tx_hash", txHash, "error", err)
 		return []byte{}, [][3]*big.Int{}, &TxHashError{GenericError{Message: "unable to fetch transaction from ETH node"}}
 	} else if isPending {
-		log.Println("ERR: Error while fetching transaction by hash from ETH rpc: transaction is still pending.")
+		slog.Error("Error while fetching transaction by hash from ETH rpc: transaction is still pending", "tx_hash", txHash)
 		return []byte{}, [][3]*big.Int{}, &PendingTxError{GenericError{Message: "transaction is still pending"}}
 	}
 
@@ -58,7 +81,7 @@ func GetCheckpointSignatures(txHash common.Hash) ([]byte, [][3]*big.Int, error)
 
 	// get Rootchain ABI to decode tx data
 	if ccAbi, err := GetABI(rootchain.RootchainABI); err != nil {
-		log.Println("ERR: Error while fetching Rootchain ABI, error:", err)
+		slog.Error("Error while fetching Rootchain ABI", "error", err)
 		return []byte{}, [][3]*big.Int{}, errors.New("unable to fetch Rootchain ABI")
 	} else {
 		rootchainABI = ccAbi
@@ -68,6 +91,69 @@ func GetCheckpointSignatures(txHash common.Hash) ([]byte, [][3]*big.Int, error)
 	return unpackDataAndSigs(payload, rootchainABI)
 }
 
+// GetTransactionCost gets the gas used by the transaction with the passed
+// hash, the effective gas price paid and the resulting cost, from its receipt.
+func GetTransactionCost(txHash common.Hash) (TransactionCost, error) {
+	// create a timed context for the RPC call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+	defer cancel()
+
+	// try to reach the ETH node
+	ethRPCClient, err := DialETH()
+	if err != nil {
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
+		return TransactionCost{}, &DialError{GenericError{Message: "unable to dial ETH node"}}
+	}
+	ethClient := ethclient.NewClient(ethRPCClient)
+
+	receipt, err := ethClient.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		slog.Error("Error while fetching transaction receipt from ETH rpc", "tx_hash", txHash, "error", err)
+		return TransactionCost{}, &TxHashError{GenericError{Message: "unable to fetch transaction receipt from ETH node"}}
+	}
+
+	// nodes which predate EIP-1559 do not return the effective gas price, in
+	// which case it is the gas price of the transaction
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		tx, _, err := ethClient.TransactionByHash(ctx, txHash)
+		if err != nil {
+			slog.Error("Error while fetching transaction by hash from ETH rpc", "tx_hash", txHash, "error", err)
+			return TransactionCost{}, &TxHashError{GenericError{Message: "unable to fetch transaction from ETH node"}}
+		}
+		gasPrice = tx.GasPrice()
+	}
+
+	return TransactionCost{
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: gasPrice,
+		Cost:              new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed)),
+	}, nil
+}
+
+// GetBalance gets the current ETH balance of the passed address, in wei.
+func GetBalance(address common.Address) (*big.Int, error) {
+	// create a timed context for the RPC call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+	defer cancel()
+
+	// try to reach the ETH node
+	ethRPCClient, err := DialETH()
+	if err != nil {
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
+		return nil, &DialError{GenericError{Message: "unable to dial ETH node"}}
+	}
+	ethClient := ethclient.NewClient(ethRPCClient)
+
+	balance, err := ethClient.BalanceAt(ctx, address, nil)
+	if err != nil {
+		slog.Error("Error while fetching balance from ETH rpc", "address", address, "error", err)
+		return nil, &DialError{GenericError{Message: "error retrieving balance, error: " + err.Error()}}
+	}
+
+	return balance, nil
+}
+
 // DecodeEvents queries the ETH RPC for activity between the range passed to it
 // for the Rootchain address. It parses all relevant events and returns them as
 // a slic of NewHeaderBlockEvent.
@@ -78,7 +164,7 @@ func DecodeEvents(startBlock uint64, endBlock uint64) ([]NewHeaderBlockEvent, er
 
 	// try to reach the ETH node
 	for i := 0; i < RETRIES; i++ {
-		ethRPCClient, err = rpc.Dial(Config.ETHRpcUrl)
+		ethRPCClient, err = DialETH()
 		if err == nil {
 			break
 		}
@@ -86,7 +172,7 @@ func DecodeEvents(startBlock uint64, endBlock uint64) ([]NewHeaderBlockEvent, er
 	}
 
 	if err != nil {
-		log.Printf("ERR: Unable to dial ETH node (%s), error: %v\n", Config.ETHRpcUrl, err)
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
 		return []NewHeaderBlockEvent{}, &DialError{GenericError{Message: "unable to dial ETH node"}}
 	}
 	ethClient := ethclient.NewClient(ethRPCClient)
@@ -95,7 +181,7 @@ func DecodeEvents(startBlock uint64, endBlock uint64) ([]NewHeaderBlockEvent, er
 
 	// get Rootchain ABI to decode tx data
 	if ccAbi, err := GetABI(rootchain.RootchainABI); err != nil {
-		log.Printf("ERR: Error while fetching Rootchain ABI, error: %v\n", err)
+		slog.Error("Error while fetching Rootchain ABI", "error", err)
 		return []NewHeaderBlockEvent{}, errors.New("unable to fetch Rootchain ABI")
 	} else {
 		rootchainABI = ccAbi
@@ -122,14 +208,14 @@ func DecodeEvents(startBlock uint64, endBlock uint64) ([]NewHeaderBlockEvent, er
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Error while trying to get logs from ETH node, error: %v\n", err)
+		slog.Error("Error while trying to get logs from ETH node", "start_block", startBlock, "end_block", endBlock, "error", err)
 		return []NewHeaderBlockEvent{}, &DialError{GenericError{Message: "unable to get logs from ETH node"}}
 	}
 
 	results := []NewHeaderBlockEvent{}
 
 	if len(logs) == 0 {
-		fmt.Println("WARN: No logs were found for given criteria.")
+		slog.Debug("No logs were found for given criteria", "start_block", startBlock, "end_block", endBlock)
 		return []NewHeaderBlockEvent{}, &NoLogsFoundError{GenericError{Message: "no logs found for given period"}}
 	}
 
@@ -142,7 +228,7 @@ func DecodeEvents(startBlock uint64, endBlock uint64) ([]NewHeaderBlockEvent, er
 		// try to unpack the event
 		err := event.Inputs.UnpackIntoMap(eventDataMap, log.Data)
 		if err != nil {
-			fmt.Printf("ERR: Could not unpack event, error: %v\n", err)
+			slog.Error("Could not unpack event", "block", log.BlockNumber, "tx_hash", log.TxHash, "error", err)
 			// in case we are unsuccessful, continue to next log
 			continue
 		}
@@ -180,7 +266,7 @@ func GetCurrentBlockNumber() (uint64, error) {
 
 	// try to reach the ETH node
 	for i := 0; i < RETRIES; i++ {
-		ethRPCClient, err = rpc.Dial(Config.ETHRpcUrl)
+		ethRPCClient, err = DialETH()
 		if err == nil {
 			break
 		}
@@ -189,7 +275,7 @@ func GetCurrentBlockNumber() (uint64, error) {
 	}
 
 	if err != nil {
-		log.Printf("ERR: Unable to dial ETH node (%s), error: %v\n", Config.ETHRpcUrl, err)
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
 		return 0, &DialError{GenericError{Message: "unable to dial ETH node"}}
 	}
 
@@ -208,7 +294,7 @@ func GetCurrentBlockNumber() (uint64, error) {
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Error while retrieving most recent block number, error: %v\n", err)
+		slog.Error("Error while retrieving most recent block number", "error", err)
 		return 0, &DialError{GenericError{Message: "error retrieving most recent block number, error: " + err.Error()}}
 	}
 
@@ -223,9 +309,9 @@ func getHeaderByNumber(blockNumber uint64) (types.Header, error) {
 	defer cancel()
 
 	// try to reach the ETH node
-	ethRPCClient, err := rpc.Dial(Config.ETHRpcUrl)
+	ethRPCClient, err := DialETH()
 	if err != nil {
-		log.Printf("ERR: Unable to dial ETH node (%s), error: %v\n", Config.ETHRpcUrl, err)
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
 		return types.Header{}, &DialError{GenericError{Message: "unable to dial ETH node"}}
 	}
 	ethClient := ethclient.NewClient(ethRPCClient)
@@ -233,7 +319,7 @@ func getHeaderByNumber(blockNumber uint64) (types.Header, error) {
 	// get the header from the RPC
 	header, err := ethClient.HeaderByNumber(ctx, big.NewInt(int64(blockNumber)))
 	if err != nil {
-		log.Printf("ERR: Unable to retrieve header from ETH node, error: %v\n", err)
+		slog.Error("Unable to retrieve header from ETH node", "block", blockNumber, "error", err)
 		return types.Header{}, &DialError{GenericError{Message: "error retrieving header, error: " + err.Error()}}
 	}
 	return *header, nil
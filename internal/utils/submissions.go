@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maticnetwork/heimdall/contracts/rootchain"
+)
+
+// FailedSubmission is a submitCheckpoint transaction which was sent to the
+// Rootchain contract, but reverted. RevertReason is empty if it could not be
+// determined.
+type FailedSubmission struct {
+	TxHash       common.Hash
+	BlockNumber  uint64
+	Timestamp    uint64
+	Sender       common.Address
+	Cost         TransactionCost
+	RevertReason string
+}
+
+// FailedSubmissionStats holds the number of failed submissions sent by a
+// validator, the ETH they burned in wei, and the timestamp of the last one.
+type FailedSubmissionStats struct {
+	Count         int
+	Cost          *big.Int
+	LastTimestamp uint64
+}
+
+// rpcBlock is the part of a block returned by eth_getBlockByNumber needed to
+// find submitCheckpoint transactions. Only these fields are decoded, so that
+// transaction types unknown to this version of go-ethereum do not cause an
+// error.
+type rpcBlock struct {
+	Timestamp    hexutil.Uint64 `json:"timestamp"`
+	Transactions []struct {
+		Hash  common.Hash     `json:"hash"`
+		From  common.Address  `json:"from"`
+		To    *common.Address `json:"to"`
+		Input hexutil.Bytes   `json:"input"`
+		Gas   hexutil.Uint64  `json:"gas"`
+		Value *hexutil.Big    `json:"value"`
+	} `json:"transactions"`
+}
+
+// FindFailedSubmissions looks through every transaction in the passed block
+// range, inclusive, for submitCheckpoint transactions sent to the Rootchain
+// contract which reverted. The revert reason is found by replaying the call on
+// the state of the previous block, so it might not always match the one of the
+// original transaction.
+func FindFailedSubmissions(startBlock uint64, endBlock uint64) ([]FailedSubmission, error) {
+	// try to reach the ETH node
+	ethRPCClient, err := DialETH()
+	if err != nil {
+		slog.Error("Unable to dial ETH node", "url", RedactURL(GetConfig().ETHRpcUrl), "error", err)
+		return nil, &DialError{GenericError{Message: "unable to dial ETH node"}}
+	}
+	defer ethRPCClient.Close()
+	ethClient := ethclient.NewClient(ethRPCClient)
+
+	rootchainABI, err := GetABI(rootchain.RootchainABI)
+	if err != nil {
+		slog.Error("Error while fetching Rootchain ABI", "error", err)
+		return nil, errors.New("unable to fetch Rootchain ABI")
+	}
+	submitCheckpointId := rootchainABI.Methods["submitCheckpoint"].ID
+	rootchainAddress := common.HexToAddress(ROOTCHAIN_ADDRESS)
+
+	results := []FailedSubmission{}
+	for blockNumber := startBlock; blockNumber <= endBlock; blockNumber++ {
+		var block rpcBlock
+
+		// retry call in case of failure
+		for i := 0; i < RETRIES; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+			err = ethRPCClient.CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(blockNumber), true)
+			cancel()
+			if err == nil {
+				break
+			}
+			time.Sleep(time.Second * RETRY_WAIT)
+		}
+		if err != nil {
+			slog.Error("Error while fetching block from ETH rpc", "block", blockNumber, "error", err)
+			return nil, &DialError{GenericError{Message: "error retrieving block, error: " + err.Error()}}
+		}
+
+		for _, tx := range block.Transactions {
+			if tx.To == nil || *tx.To != rootchainAddress || !bytes.HasPrefix(tx.Input, submitCheckpointId) {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+			receipt, err := ethClient.TransactionReceipt(ctx, tx.Hash)
+			cancel()
+			if err != nil {
+				slog.Error("Error while fetching transaction receipt from ETH rpc", "tx_hash", tx.Hash, "error", err)
+				return nil, &TxHashError{GenericError{Message: "unable to fetch transaction receipt from ETH node"}}
+			}
+			if receipt.Status != types.ReceiptStatusFailed {
+				continue
+			}
+
+			gasPrice := receipt.EffectiveGasPrice
+			if gasPrice == nil {
+				gasPrice = new(big.Int)
+			}
+
+			submission := FailedSubmission{
+				TxHash:      tx.Hash,
+				BlockNumber: blockNumber,
+				Timestamp:   uint64(block.Timestamp),
+				Sender:      tx.From,
+				Cost: TransactionCost{
+					GasUsed:           receipt.GasUsed,
+					EffectiveGasPrice: gasPrice,
+					Cost:              new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed)),
+				},
+			}
+
+			// replay the call to get the revert reason
+			call := ethereum.CallMsg{From: tx.From, To: tx.To, Gas: uint64(tx.Gas), Data: tx.Input}
+			if tx.Value != nil {
+				call.Value = tx.Value.ToInt()
+			}
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second*TIMEOUT)
+			_, err = ethClient.CallContract(ctx, call, new(big.Int).SetUint64(blockNumber-1))
+			cancel()
+			if err != nil {
+				submission.RevertReason = strings.TrimPrefix(err.Error(), "execution reverted: ")
+			}
+
+			slog.Warn("Found failed checkpoint submission", "tx_hash", tx.Hash, "block", blockNumber, "sender", tx.From, "reason", submission.RevertReason)
+			results = append(results, submission)
+		}
+	}
+
+	return results, nil
+}
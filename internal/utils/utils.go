@@ -1,11 +1,9 @@
 package utils
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
+	"log/slog"
+	"math"
 	"math/big"
 	"os"
 	"sort"
@@ -17,70 +15,41 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-var configPath = "config/config.json"
-var Config = GeneralSettings{}
-
 const MAX_DEPOSITS = 10000
 const ROOTCHAIN_ADDRESS = "0x86E4Dc95c7FBdBf52e33D563BbDB00823894C287"
 const STAKEMANAGER_ADDRESS = "0x5e3Ef299fDDf15eAa0432E6e66473ace8c13D908"
 const RETRIES = 3
 const RETRY_WAIT = 3
 const TIMEOUT = 300
-
-// GeneralSettings is the representation of the options that can be
-// contained in the config JSON file.
-type GeneralSettings struct {
-	ETHRpcUrl         string   `json:"ETHRpcUrl"`
-	PrometheusPort    string   `json:"PrometheusPort"`
-	DatabaseLocation  string   `json:"DatabaseLocation"`
-	PublicKeys        []string `json:"PublicKeys"`
-	ContinueFromBlock int      `json:"ContinueFromBlock"`
-}
-
-// updateConfigPath udpates the path to where the config file is located.
-func UpdateConfigPath(path string) {
-	configPath = path
-	Config = openConfig(configPath)
-}
-
-// openConfig opens the config specified in the path path. It returns the
-// parsed config as a GeneralSettings object.
-func openConfig(path string) GeneralSettings {
-	// open the passed file
-	file, err := os.Open(path)
-	if err != nil {
-		log.Fatal("ERR Error while opening config file (", path, "): ", err)
-	}
-	defer file.Close()
-
-	// read the file
-	content, err := io.ReadAll(file)
-	if err != nil {
-		log.Fatal("ERR Error while reading content of config file (", path, "): ", err)
-	}
-
-	// unmarshal JSON data
-	var config GeneralSettings
-	err = json.Unmarshal(content, &config)
-	if err != nil {
-		log.Fatal("ERR Error while unmarshaling config file (", path, "): ", err)
-	}
-
-	return config
-}
+const MAX_BACKOFF = 600
+const LOOP_INTERVAL = 60
+const CADENCE_CHECKPOINTS = 100
+const PROPOSER_BONUS = 10
+const BALANCE_INTERVAL = 300
+const FAILED_SUBMISSIONS_BATCH = 100
+const VERIFICATION_BATCH = 10
+const DEFAULT_LOW_BALANCE_THRESHOLD = 0.1
+const DEFAULT_STALL_MULTIPLIER = 3
+const DEFAULT_MAX_HEAD_LAG = 100
+const MIN_TOKEN_LENGTH = 16
+
+// the number of checkpoints the performance benchmark is calculated over, and
+// the factor the median performance is multiplied by
+const PB_WINDOW = 700
+const PB_FACTOR = 0.95
 
 // convertSignature takes a signature in the form of a big.Int array, and
 // converts them to bytes in the order that can be processed by other functions.
 func convertSignature(sig [3]*big.Int) ([]byte, error) {
 	// ensure 'v' is no longer than 1 byte
 	if sig[2].BitLen() > 8 {
-		fmt.Println("ERR: Signature 'v' length is longer than 1 byte.")
+		slog.Error("Signature 'v' length is longer than 1 byte")
 		return nil, errors.New("length of 'v' in signature is longer than 1 byte")
 	}
 
 	// check that v is either 27 or 28
 	if !(sig[2].Cmp(big.NewInt(27)) == 0 || sig[2].Cmp(big.NewInt(28)) == 0) {
-		fmt.Printf("ERR: Signature 'v' value is %d, expected 27 or 28.\n", sig[2])
+		slog.Error("Signature 'v' value is not 27 or 28", "v", sig[2].String())
 		return nil, errors.New("value of 'v' in signature is neither 27 or 28")
 	}
 
@@ -89,7 +58,7 @@ func convertSignature(sig [3]*big.Int) ([]byte, error) {
 
 	// ensure the signature is valid
 	if !crypto.ValidateSignatureValues(v, sig[0], sig[1], true) {
-		fmt.Println("ERR: Signature is not valid.")
+		slog.Error("Signature is not valid")
 		return nil, errors.New("signature is invalid")
 	}
 
@@ -114,7 +83,7 @@ func recoverAddress(msg []byte, sig []byte) (string, error) {
 	// get the public key using hash and signature
 	sigPublicKeyECDSA, err := crypto.SigToPub(msg, sig)
 	if err != nil {
-		fmt.Printf("ERR: Could not convert hash and signature to public key, error: %v\n", err)
+		slog.Error("Could not convert hash and signature to public key", "error", err)
 		return "", err
 	}
 
@@ -179,7 +148,7 @@ func GetBlockTimestamp(blockNumber uint64) (uint64, error) {
 	}
 
 	if err != nil {
-		fmt.Printf("ERR: Error while trying to get timestamp of block number %d, error: %v\n", blockNumber, err)
+		slog.Error("Error while trying to get timestamp of block", "block", blockNumber, "error", err)
 		return 0, err
 	}
 
@@ -211,7 +180,7 @@ func ContainsString(stringSlice []string, search string) bool {
 // CheckIfDBExists is a simple function that checks if the database located as
 // specified in the config exists or not.
 func CheckIfDBExists() (bool, error) {
-	_, err := os.Stat(Config.DatabaseLocation)
+	_, err := os.Stat(GetConfig().DatabaseLocation)
 	if err != nil {
 		return false, err
 	}
@@ -222,8 +191,9 @@ func CheckIfDBExists() (bool, error) {
 // contains a '*', and is one element long. In such case, we are tracking the
 // performance of all the validators in the set.
 func CheckIfTrackAll() bool {
-	if len(Config.PublicKeys) == 1 {
-		return Config.PublicKeys[0] == "*"
+	publicKeys := GetConfig().PublicKeys
+	if len(publicKeys) == 1 {
+		return publicKeys[0] == "*"
 	}
 	return false
 }
@@ -248,3 +218,24 @@ func Median(data []float64) float64 {
 
 	return median
 }
+
+// Percentile calculates the passed percentile (between 0 and 1) from a slice of
+// floats, interpolating linearly between the closest values. The 0.5
+// percentile is the same as the median.
+func Percentile(data []float64, percentile float64) float64 {
+	dataCopy := make([]float64, len(data))
+	copy(dataCopy, data)
+
+	sort.Float64s(dataCopy)
+
+	l := len(dataCopy)
+	if l == 0 {
+		return 0
+	}
+
+	position := percentile * float64(l-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+
+	return dataCopy[lower] + (dataCopy[upper]-dataCopy[lower])*(position-float64(lower))
+}
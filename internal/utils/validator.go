@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -12,6 +17,14 @@ type Validator struct {
 	DeactivationEpoch uint64
 	OwnerAddress      common.Address
 	SignerAddress     common.Address
+	// Stake is the validator's own stake plus the stake delegated to it, in
+	// wei, of which DelegatedStake was delegated. CommissionRate is the
+	// percentage of the delegators' rewards kept by the validator. They are
+	// only set for validators fetched from the StakeManager contract, and are
+	// not compared by CompareValidators.
+	Stake          *big.Int
+	DelegatedStake *big.Int
+	CommissionRate uint64
 }
 
 // ValidatorError contains a Validator, and an Error. It is used by concurrent
@@ -37,3 +50,171 @@ func CompareValidators(validator1 Validator, validator2 Validator) bool {
 	}
 	return false
 }
+
+// OTHER_GROUP is the name of the group made up of the validators in the set
+// which are not part of any configured group.
+const OTHER_GROUP = "other"
+
+// TrackedValidator identifies a validator whose performance is tracked. Only
+// one of ID, Owner or Signer is set. Tracking by ID or owner address keeps
+// working when the validator changes its signer key. Name, Team and Groups are
+// optional, human-readable metadata used to label the validator's metrics.
+type TrackedValidator struct {
+	ID     int      `json:"ID" yaml:"ID" toml:"ID"`
+	Owner  string   `json:"Owner" yaml:"Owner" toml:"Owner"`
+	Signer string   `json:"Signer" yaml:"Signer" toml:"Signer"`
+	Name   string   `json:"Name" yaml:"Name" toml:"Name"`
+	Team   string   `json:"Team" yaml:"Team" toml:"Team"`
+	Groups []string `json:"Groups" yaml:"Groups" toml:"Groups"`
+}
+
+// String returns the tracked validator in the form used by
+// ParseTrackedValidator, e.g. id:123.
+func (v TrackedValidator) String() string {
+	switch {
+	case v.ID != 0:
+		return "id:" + strconv.Itoa(v.ID)
+	case v.Owner != "":
+		return "owner:" + strings.ToLower(v.Owner)
+	default:
+		return "signer:" + strings.ToLower(v.Signer)
+	}
+}
+
+// ParseTrackedValidator parses a tracked validator in the form id:<id>,
+// owner:<address> or signer:<address>. A plain address is taken to be a signer
+// address.
+func ParseTrackedValidator(text string) (TrackedValidator, error) {
+	kind, value, found := strings.Cut(strings.TrimSpace(text), ":")
+	if !found {
+		kind, value = "signer", kind
+	}
+
+	switch strings.ToLower(kind) {
+	case "id":
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return TrackedValidator{}, fmt.Errorf("%q is not a valid validator ID", value)
+		}
+		return TrackedValidator{ID: id}, nil
+	case "owner":
+		return TrackedValidator{Owner: value}, nil
+	case "signer":
+		return TrackedValidator{Signer: value}, nil
+	default:
+		return TrackedValidator{}, fmt.Errorf("unknown validator identifier %q, expected id, owner or signer", kind)
+	}
+}
+
+// Validate checks that exactly one of the fields identifying the validator is
+// set, and that it is valid.
+func (v TrackedValidator) Validate() error {
+	set := 0
+	if v.ID != 0 {
+		set++
+		if v.ID < 0 {
+			return fmt.Errorf("ID must be positive")
+		}
+	}
+	if v.Owner != "" {
+		set++
+		if !common.IsHexAddress(v.Owner) {
+			return fmt.Errorf("Owner %q is not a valid address", v.Owner)
+		}
+	}
+	if v.Signer != "" {
+		set++
+		if !common.IsHexAddress(v.Signer) {
+			return fmt.Errorf("Signer %q is not a valid address", v.Signer)
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("exactly one of ID, Owner or Signer must be set")
+	}
+
+	for _, group := range v.Groups {
+		if strings.TrimSpace(group) == "" {
+			return fmt.Errorf("Groups must not contain empty names")
+		}
+		if strings.EqualFold(group, OTHER_GROUP) {
+			return fmt.Errorf("Groups must not contain %q, as it is reserved for the rest of the validator set", OTHER_GROUP)
+		}
+	}
+
+	return nil
+}
+
+// Matches returns true if the passed validator is the one identified by the
+// tracked validator.
+func (v TrackedValidator) Matches(validator Validator) bool {
+	switch {
+	case v.ID != 0:
+		return v.ID == validator.ValidatorId
+	case v.Owner != "":
+		return strings.EqualFold(v.Owner, validator.OwnerAddress.String())
+	default:
+		return strings.EqualFold(v.Signer, validator.SignerAddress.String())
+	}
+}
+
+// ValidatorMetadata returns the name, team and groups configured for the
+// passed validator, either in Validators or in the ValidatorMetadataFile. The
+// entries in Validators take priority. If the validator has no metadata, an
+// empty TrackedValidator is returned.
+func ValidatorMetadata(validator Validator) TrackedValidator {
+	config := GetConfig()
+	for _, entries := range [][]TrackedValidator{config.Validators, config.ValidatorMetadata} {
+		for _, entry := range entries {
+			if entry.Matches(validator) && (entry.Name != "" || entry.Team != "" || len(entry.Groups) > 0) {
+				return entry
+			}
+		}
+	}
+	return TrackedValidator{}
+}
+
+// GroupsConfigured returns true if any validator is assigned to a group,
+// either in Validators or in the ValidatorMetadataFile.
+func GroupsConfigured() bool {
+	config := GetConfig()
+	for _, entries := range [][]TrackedValidator{config.Validators, config.ValidatorMetadata} {
+		for _, entry := range entries {
+			if len(entry.Groups) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InPerformanceBenchmark returns true if the validator was active for the whole
+// PB_WINDOW checkpoint range ending at the passed checkpoint, meaning it is
+// counted when calculating the performance benchmark.
+func (validator Validator) InPerformanceBenchmark(checkpointNumber uint64) bool {
+	return validator.ActiveForWindow(checkpointNumber, PB_WINDOW)
+}
+
+// ActiveForWindow returns true if the validator was active for the whole range
+// of the passed number of checkpoints ending at the passed checkpoint.
+func (validator Validator) ActiveForWindow(checkpointNumber uint64, window int) bool {
+	var rangeStart uint64
+	if checkpointNumber > uint64(window-1) {
+		rangeStart = checkpointNumber - uint64(window-1)
+	}
+	return validator.DeactivationEpoch == 0 && validator.ActivationEpoch <= rangeStart
+}
+
+// TrackedValidators returns all the validators being tracked, that is the ones
+// in Validators plus the signer keys in PublicKeys. It does not include the
+// '*' entry, which is checked with CheckIfTrackAll.
+func TrackedValidators() []TrackedValidator {
+	config := GetConfig()
+	tracked := []TrackedValidator{}
+	for _, publicKey := range config.PublicKeys {
+		if publicKey != "*" {
+			tracked = append(tracked, TrackedValidator{Signer: publicKey})
+		}
+	}
+	return append(tracked, config.Validators...)
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFAULT_PERFORMANCE_WINDOWS are the windows used when none are set in the
+// config.
+var DEFAULT_PERFORMANCE_WINDOWS = []string{"700", "total"}
+
+// PerformanceWindow is a range over which the performance of validators is
+// calculated. It is either the last number of checkpoints, the checkpoints
+// within the last duration (up to the last checkpoint processed), or all the
+// checkpoints processed.
+type PerformanceWindow struct {
+	Name        string
+	Checkpoints int
+	Duration    time.Duration
+	Total       bool
+}
+
+// ParsePerformanceWindow parses a performance window, which is either "total",
+// a number of checkpoints (e.g. 700) or a duration in minutes, hours or days
+// (e.g. 30m, 24h or 7d). The name of the window is normalised, so that the same
+// window always has the same name (e.g. 0700 is named 700, and 24h is named
+// 1d).
+func ParsePerformanceWindow(text string) (PerformanceWindow, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	if text == "total" {
+		return PerformanceWindow{Name: text, Total: true}, nil
+	}
+
+	if checkpoints, err := strconv.Atoi(text); err == nil {
+		if checkpoints < 1 {
+			return PerformanceWindow{}, fmt.Errorf("window %q must be at least 1 checkpoint", text)
+		}
+		return PerformanceWindow{Name: strconv.Itoa(checkpoints), Checkpoints: checkpoints}, nil
+	}
+
+	var duration time.Duration
+	var err error
+	if days, found := strings.CutSuffix(text, "d"); found {
+		var number int
+		number, err = strconv.Atoi(days)
+		duration = time.Duration(number) * 24 * time.Hour
+	} else {
+		duration, err = time.ParseDuration(text)
+	}
+	if err != nil {
+		return PerformanceWindow{}, fmt.Errorf("window %q is not a number of checkpoints, a duration (e.g. 24h or 7d) or \"total\"", text)
+	}
+	if duration <= 0 {
+		return PerformanceWindow{}, fmt.Errorf("window %q must be positive", text)
+	}
+
+	return PerformanceWindow{Name: durationWindowName(duration), Duration: duration}, nil
+}
+
+// durationWindowName returns the name of a window covering the passed
+// duration, in the largest unit of days, hours or minutes which it is a whole
+// number of.
+func durationWindowName(duration time.Duration) string {
+	switch {
+	case duration%(24*time.Hour) == 0:
+		return strconv.Itoa(int(duration/(24*time.Hour))) + "d"
+	case duration%time.Hour == 0:
+		return strconv.Itoa(int(duration/time.Hour)) + "h"
+	case duration%time.Minute == 0:
+		return strconv.Itoa(int(duration/time.Minute)) + "m"
+	default:
+		return duration.String()
+	}
+}
+
+// PerformanceWindows returns the performance windows set in the config, or the
+// default ones if none are set. The config is expected to be validated, so
+// windows which cannot be parsed are skipped.
+func PerformanceWindows() []PerformanceWindow {
+	names := GetConfig().PerformanceWindows
+	if len(names) == 0 {
+		names = DEFAULT_PERFORMANCE_WINDOWS
+	}
+
+	windows := []PerformanceWindow{}
+	for _, name := range names {
+		window, err := ParsePerformanceWindow(name)
+		if err == nil {
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+// WindowCounters holds the running counters of a performance window: the range
+// of checkpoints it covers, the number of checkpoints within that range, and
+// the number of those signed by each validator, keyed by validator id.
+type WindowCounters struct {
+	Window      string
+	Start       int
+	End         int
+	Checkpoints int
+	Signed      map[int]int
+}
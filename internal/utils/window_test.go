@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePerformanceWindow(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected PerformanceWindow
+		invalid  bool
+	}{
+		{text: "total", expected: PerformanceWindow{Name: "total", Total: true}},
+		{text: " Total ", expected: PerformanceWindow{Name: "total", Total: true}},
+		{text: "700", expected: PerformanceWindow{Name: "700", Checkpoints: 700}},
+		{text: "0700", expected: PerformanceWindow{Name: "700", Checkpoints: 700}},
+		{text: "1", expected: PerformanceWindow{Name: "1", Checkpoints: 1}},
+		{text: "30m", expected: PerformanceWindow{Name: "30m", Duration: 30 * time.Minute}},
+		{text: "90m", expected: PerformanceWindow{Name: "90m", Duration: 90 * time.Minute}},
+		{text: "120m", expected: PerformanceWindow{Name: "2h", Duration: 2 * time.Hour}},
+		{text: "24h", expected: PerformanceWindow{Name: "1d", Duration: 24 * time.Hour}},
+		{text: "36h", expected: PerformanceWindow{Name: "36h", Duration: 36 * time.Hour}},
+		{text: "7d", expected: PerformanceWindow{Name: "7d", Duration: 7 * 24 * time.Hour}},
+		{text: "7D", expected: PerformanceWindow{Name: "7d", Duration: 7 * 24 * time.Hour}},
+		{text: "90s", expected: PerformanceWindow{Name: "1m30s", Duration: 90 * time.Second}},
+		{text: "0", invalid: true},
+		{text: "-5", invalid: true},
+		{text: "0d", invalid: true},
+		{text: "-1h", invalid: true},
+		{text: "1.5d", invalid: true},
+		{text: "week", invalid: true},
+		{text: "", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			window, err := ParsePerformanceWindow(test.text)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("ParsePerformanceWindow(%q) = %+v, expected an error", test.text, window)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePerformanceWindow(%q) returned error: %v", test.text, err)
+			}
+			if window != test.expected {
+				t.Errorf("ParsePerformanceWindow(%q) = %+v, expected %+v", test.text, window, test.expected)
+			}
+		})
+	}
+}
+
+func TestPerformanceWindows(t *testing.T) {
+	previous := GetConfig()
+	t.Cleanup(func() { SetConfig(*previous) })
+
+	tests := []struct {
+		name     string
+		windows  []string
+		expected []string
+	}{
+		{"default", nil, DEFAULT_PERFORMANCE_WINDOWS},
+		{"configured", []string{"24h", "100", "total"}, []string{"1d", "100", "total"}},
+		{"invalid skipped", []string{"100", "week"}, []string{"100"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetConfig(GeneralSettings{PerformanceWindows: test.windows})
+
+			windows := PerformanceWindows()
+			if len(windows) != len(test.expected) {
+				t.Fatalf("PerformanceWindows() = %+v, expected windows %v", windows, test.expected)
+			}
+			for i, window := range windows {
+				if window.Name != test.expected[i] {
+					t.Errorf("PerformanceWindows() = %+v, expected windows %v", windows, test.expected)
+				}
+			}
+		})
+	}
+}